@@ -10,8 +10,8 @@ import (
 	"syscall"
 	"time"
 
+	"github.com/anton1ks96/college-core-api/internal/client/embedding"
 	"github.com/anton1ks96/college-core-api/internal/client/llm"
-	"github.com/anton1ks96/college-core-api/internal/client/tei"
 	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/handlers"
 	"github.com/anton1ks96/college-core-api/internal/repository"
@@ -40,15 +40,35 @@ func Run() {
 	}
 	defer qdrantClient.Close()
 
-	llmClient := llm.NewClient(cfg)
-	teiClient := tei.NewClient(cfg)
+	llmClient, err := llm.NewClient(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	var embeddingCache repository.EmbeddingCacheRepository
+	if cfg.TEI.EmbeddingCache {
+		embeddingCache = repository.NewEmbeddingCacheRepository(cfg, db)
+	}
+	embedder, err := embedding.NewEmbedder(cfg, embeddingCache)
+	if err != nil {
+		logger.Fatal(err)
+	}
+	reranker, err := embedding.NewReranker(cfg)
+	if err != nil {
+		logger.Fatal(err)
+	}
 
 	vectorRepo := repository.NewVectorRepository(cfg, qdrantClient)
+	answerCacheRepo := repository.NewAnswerCacheRepository(cfg, qdrantClient)
 
 	ctx := context.Background()
 	if err := vectorRepo.EnsureCollection(ctx, uint64(cfg.RAG.VectorSize)); err != nil {
 		logger.Fatal(err)
 	}
+	if cfg.RAG.AnswerCache.Enabled {
+		if err := answerCacheRepo.EnsureCollection(ctx, uint64(cfg.RAG.VectorSize)); err != nil {
+			logger.Fatal(err)
+		}
+	}
 
 	datasetRepo := repository.NewDatasetRepository(cfg, db)
 	fileRepo, err := repository.NewFileRepository(cfg)
@@ -58,6 +78,10 @@ func Run() {
 	topicRepo := repository.NewTopicRepository(cfg, db)
 	datasetPermissionRepo := repository.NewDatasetPermissionRepository(cfg, db)
 	savedChatRepo := repository.NewSavedChatRepository(cfg, db)
+	indexJobRepo := repository.NewIndexJobRepository(cfg, db)
+	datasetVersionRepo := repository.NewDatasetVersionRepository(cfg, db)
+	usageRepo := repository.NewUsageRepository(cfg, db)
+	cleanupTaskRepo := repository.NewCleanupTaskRepository(cfg, db)
 
 	repos := &services.Repositories{
 		Dataset:           datasetRepo,
@@ -66,11 +90,18 @@ func Run() {
 		DatasetPermission: datasetPermissionRepo,
 		SavedChat:         savedChatRepo,
 		Vector:            vectorRepo,
+		IndexJob:          indexJobRepo,
+		CleanupTask:       cleanupTaskRepo,
+		DatasetVersion:    datasetVersionRepo,
+		Usage:             usageRepo,
+		AnswerCache:       answerCacheRepo,
+		EmbeddingCache:    embeddingCache,
 	}
 
 	clients := &services.Clients{
-		LLM: llmClient,
-		TEI: teiClient,
+		LLM:      llmClient,
+		Embedder: embedder,
+		Reranker: reranker,
 	}
 
 	servicesInstance := services.NewServices(services.Deps{
@@ -79,6 +110,13 @@ func Run() {
 		Config:  cfg,
 	})
 
+	workersCtx, stopWorkers := context.WithCancel(context.Background())
+	servicesInstance.Index.Start(workersCtx)
+	servicesInstance.Cleanup.Start(workersCtx)
+	servicesInstance.EmbeddingCache.Start(workersCtx)
+	servicesInstance.Reconcile.Start(workersCtx)
+	servicesInstance.Trash.Start(workersCtx)
+
 	handler := handlers.NewHandler(servicesInstance, cfg)
 
 	router := handler.Init()
@@ -106,5 +144,12 @@ func Run() {
 		logger.Error(fmt.Errorf("server forced to shutdown: %w", err))
 	}
 
+	stopWorkers()
+	servicesInstance.Index.Wait()
+	servicesInstance.Cleanup.Wait()
+	servicesInstance.EmbeddingCache.Wait()
+	servicesInstance.Reconcile.Wait()
+	servicesInstance.Trash.Wait()
+
 	logger.Info("Server exited")
 }
@@ -0,0 +1,171 @@
+package embedding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sync"
+
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// provider — протокол конкретного сервера эмбеддингов
+type provider interface {
+	// embedBatch отправляет один батч, не больше embedBatchSize текстов
+	embedBatch(ctx context.Context, inputs []string) ([][]float32, error)
+	// modelID возвращает идентификатор модели, часть ключа кэша
+	modelID(ctx context.Context) (string, error)
+}
+
+// client добавляет к провайдеру кэш, разбиение на батчи и параллельную отправку
+type client struct {
+	provider    provider
+	concurrency int
+	cache       EmbeddingCache
+}
+
+// Embed служит для вопросов пользователей и идёт мимо кэша: вопросы почти не повторяются,
+// а кэш предназначен для фрагментов, которые заново эмбеддятся при переиндексации
+func (c *client) Embed(ctx context.Context, input string) ([]float32, error) {
+	vectors, err := c.embedUncached(ctx, []string{input})
+	if err != nil {
+		return nil, err
+	}
+	if len(vectors) == 0 {
+		return nil, fmt.Errorf("empty embedding response")
+	}
+	return vectors[0], nil
+}
+
+const embedBatchSize = 32
+
+// EmbedBatch возвращает эмбеддинги в порядке inputs. Тексты, уже встречавшиеся с той же моделью,
+// берутся из кэша, одинаковые тексты внутри батча отправляются провайдеру один раз
+func (c *client) EmbedBatch(ctx context.Context, inputs []string) ([][]float32, error) {
+	if c.cache == nil {
+		return c.embedUncached(ctx, inputs)
+	}
+
+	model, err := c.provider.modelID(ctx)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("embedding cache disabled for this batch: %v", err))
+		return c.embedUncached(ctx, inputs)
+	}
+
+	hashes := make([]string, len(inputs))
+	unique := make([]string, 0, len(inputs))
+	seen := make(map[string]bool, len(inputs))
+	for i, input := range inputs {
+		sum := sha256.Sum256([]byte(input))
+		hashes[i] = hex.EncodeToString(sum[:])
+		if !seen[hashes[i]] {
+			seen[hashes[i]] = true
+			unique = append(unique, hashes[i])
+		}
+	}
+
+	vectors, err := c.cache.GetEmbeddings(ctx, model, unique)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("failed to read embedding cache: %v", err))
+		vectors = make(map[string][]float32)
+	}
+
+	missing := make([]string, 0)
+	missingHashes := make([]string, 0)
+	queued := make(map[string]bool)
+	for i, input := range inputs {
+		if _, ok := vectors[hashes[i]]; ok || queued[hashes[i]] {
+			continue
+		}
+		queued[hashes[i]] = true
+		missing = append(missing, input)
+		missingHashes = append(missingHashes, hashes[i])
+	}
+
+	if len(missing) > 0 {
+		embedded, err := c.embedUncached(ctx, missing)
+		if err != nil {
+			return nil, err
+		}
+
+		fresh := make(map[string][]float32, len(embedded))
+		for i, vector := range embedded {
+			fresh[missingHashes[i]] = vector
+			vectors[missingHashes[i]] = vector
+		}
+
+		if err := c.cache.PutEmbeddings(ctx, model, fresh); err != nil {
+			logger.Warn(fmt.Sprintf("failed to write embedding cache: %v", err))
+		}
+	}
+
+	result := make([][]float32, len(inputs))
+	for i := range inputs {
+		result[i] = vectors[hashes[i]]
+	}
+
+	return result, nil
+}
+
+// embedUncached отправляет батчи провайдеру параллельно, не больше concurrency одновременно.
+// Первая ошибка отменяет остальные батчи
+func (c *client) embedUncached(ctx context.Context, inputs []string) ([][]float32, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	batches := (len(inputs) + embedBatchSize - 1) / embedBatchSize
+	results := make([][][]float32, batches)
+	sem := make(chan struct{}, c.concurrency)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for b := 0; b < batches; b++ {
+		start := b * embedBatchSize
+		end := min(start+embedBatchSize, len(inputs))
+
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(b int, batch []string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			vectors, err := c.provider.embedBatch(ctx, batch)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = err
+					cancel()
+				})
+				return
+			}
+			results[b] = vectors
+		}(b, inputs[start:end])
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	result := make([][]float32, 0, len(inputs))
+	for _, vectors := range results {
+		result = append(result, vectors...)
+	}
+
+	return result, nil
+}
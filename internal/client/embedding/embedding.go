@@ -0,0 +1,121 @@
+package embedding
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+)
+
+const (
+	ProviderTEI    = "tei"
+	ProviderOpenAI = "openai"
+	ProviderCohere = "cohere"
+	ProviderNone   = "none"
+)
+
+var (
+	ErrEmbeddingUnavailable = errors.New("embedding service unavailable")
+	ErrRerankerUnavailable  = errors.New("reranker service unavailable")
+)
+
+// Embedder превращает тексты в векторы. EmbedBatch возвращает векторы в порядке inputs
+type Embedder interface {
+	Embed(ctx context.Context, input string) ([]float32, error)
+	EmbedBatch(ctx context.Context, inputs []string) ([][]float32, error)
+}
+
+// Reranker оценивает релевантность texts запросу. Порядок результатов не гарантируется
+type Reranker interface {
+	Rerank(ctx context.Context, query string, texts []string) ([]RerankResult, error)
+}
+
+type RerankResult struct {
+	Index int     `json:"index"`
+	Score float64 `json:"score"`
+}
+
+// EmbeddingCache хранит эмбеддинги по идентификатору модели и sha256 текста
+type EmbeddingCache interface {
+	GetEmbeddings(ctx context.Context, model string, hashes []string) (map[string][]float32, error)
+	PutEmbeddings(ctx context.Context, model string, vectors map[string][]float32) error
+}
+
+// NewEmbedder создаёт эмбеддер выбранного в конфиге провайдера. При cache == nil каждый текст
+// отправляется провайдеру
+func NewEmbedder(cfg *config.Config, cache EmbeddingCache) (Embedder, error) {
+	name := providerName(cfg.TEI.EmbeddingProvider)
+	t := newTransport(cfg, cfg.TEI.EmbeddingApiKey)
+	svc := newService(cfg, name+" embeddings", cfg.TEI.EmbeddingsURL, ErrEmbeddingUnavailable)
+
+	var p provider
+	switch name {
+	case ProviderTEI:
+		p = &teiEmbedder{transport: t, svc: svc, model: cfg.TEI.EmbeddingModel}
+	case ProviderOpenAI:
+		if cfg.TEI.EmbeddingModel == "" {
+			return nil, fmt.Errorf("embedding model is required for provider %q", ProviderOpenAI)
+		}
+		p = &openaiEmbedder{transport: t, svc: svc, model: cfg.TEI.EmbeddingModel}
+	default:
+		return nil, fmt.Errorf("unknown embedding provider: %s", cfg.TEI.EmbeddingProvider)
+	}
+
+	return &client{
+		provider:    p,
+		concurrency: max(cfg.TEI.MaxConcurrency, 1),
+		cache:       cache,
+	}, nil
+}
+
+// NewReranker создаёт реранкер выбранного в конфиге провайдера.
+// Для провайдера none возвращается nil: поиск обходится без реранжирования
+func NewReranker(cfg *config.Config) (Reranker, error) {
+	name := providerName(cfg.TEI.RerankerProvider)
+	t := newTransport(cfg, cfg.TEI.RerankerApiKey)
+	svc := newService(cfg, name+" reranker", cfg.TEI.RerankerURL, ErrRerankerUnavailable)
+
+	switch name {
+	case ProviderTEI:
+		return &teiReranker{transport: t, svc: svc}, nil
+	case ProviderCohere:
+		return &cohereReranker{transport: t, svc: svc, model: cfg.TEI.RerankerModel}, nil
+	case ProviderNone:
+		return nil, nil
+	default:
+		return nil, fmt.Errorf("unknown reranker provider: %s", cfg.TEI.RerankerProvider)
+	}
+}
+
+// providerName нормализует имя провайдера, пустое значение означает TEI
+func providerName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	if name == "" {
+		return ProviderTEI
+	}
+	return name
+}
+
+func newTransport(cfg *config.Config, apiKey string) *transport {
+	return &transport{
+		httpClient: &http.Client{
+			Timeout: cfg.TEI.Timeout,
+		},
+		apiKey:       apiKey,
+		maxRetries:   max(cfg.TEI.MaxRetries, 0),
+		retryBackoff: cfg.TEI.RetryBackoff,
+		maxBackoff:   cfg.TEI.MaxBackoff,
+	}
+}
+
+func newService(cfg *config.Config, name, url string, unavailable error) *service {
+	return &service{
+		name:        name,
+		url:         strings.TrimRight(url, "/"),
+		unavailable: unavailable,
+		breaker:     &breaker{threshold: cfg.TEI.BreakerThreshold, cooldown: cfg.TEI.BreakerCooldown},
+	}
+}
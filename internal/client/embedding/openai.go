@@ -0,0 +1,85 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+)
+
+// openaiEmbedder работает с OpenAI-совместимым /v1/embeddings (vLLM, LocalAI, Ollama).
+// Такие серверы не сообщают версию модели, поэтому ключом кэша служит имя модели из конфига
+type openaiEmbedder struct {
+	transport *transport
+	svc       *service
+	model     string
+}
+
+func (e *openaiEmbedder) embedBatch(ctx context.Context, inputs []string) ([][]float32, error) {
+	body := struct {
+		Model string   `json:"model"`
+		Input []string `json:"input"`
+	}{Model: e.model, Input: inputs}
+
+	var resp struct {
+		Data []struct {
+			Index     int       `json:"index"`
+			Embedding []float32 `json:"embedding"`
+		} `json:"data"`
+	}
+	if err := e.transport.postJSON(ctx, e.svc, "/v1/embeddings", body, &resp); err != nil {
+		return nil, err
+	}
+	if len(resp.Data) != len(inputs) {
+		return nil, fmt.Errorf("openai embeddings returned %d vectors for %d inputs", len(resp.Data), len(inputs))
+	}
+
+	// порядок data не обязан совпадать с input, сопоставляем по index
+	vectors := make([][]float32, len(inputs))
+	for _, item := range resp.Data {
+		if item.Index < 0 || item.Index >= len(inputs) || vectors[item.Index] != nil {
+			return nil, fmt.Errorf("openai embeddings returned invalid index %d", item.Index)
+		}
+		vectors[item.Index] = item.Embedding
+	}
+
+	return vectors, nil
+}
+
+func (e *openaiEmbedder) modelID(ctx context.Context) (string, error) {
+	return e.model, nil
+}
+
+// cohereReranker работает с Cohere/Jina-совместимым /v1/rerank
+type cohereReranker struct {
+	transport *transport
+	svc       *service
+	model     string
+}
+
+func (r *cohereReranker) Rerank(ctx context.Context, query string, texts []string) ([]RerankResult, error) {
+	body := struct {
+		Model     string   `json:"model,omitempty"`
+		Query     string   `json:"query"`
+		Documents []string `json:"documents"`
+		TopN      int      `json:"top_n"`
+	}{Model: r.model, Query: query, Documents: texts, TopN: len(texts)}
+
+	var resp struct {
+		Results []struct {
+			Index          int     `json:"index"`
+			RelevanceScore float64 `json:"relevance_score"`
+		} `json:"results"`
+	}
+	if err := r.transport.postJSON(ctx, r.svc, "/v1/rerank", body, &resp); err != nil {
+		return nil, err
+	}
+
+	results := make([]RerankResult, 0, len(resp.Results))
+	for _, item := range resp.Results {
+		if item.Index < 0 || item.Index >= len(texts) {
+			return nil, fmt.Errorf("rerank returned invalid index %d", item.Index)
+		}
+		results = append(results, RerankResult{Index: item.Index, Score: item.RelevanceScore})
+	}
+
+	return results, nil
+}
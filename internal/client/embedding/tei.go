@@ -0,0 +1,82 @@
+package embedding
+
+import (
+	"context"
+	"fmt"
+	"sync"
+)
+
+// teiEmbedder работает с text-embeddings-inference: POST /embed, модель берётся из /info
+type teiEmbedder struct {
+	transport *transport
+	svc       *service
+
+	// model — идентификатор модели из конфига или /info
+	modelMu sync.Mutex
+	model   string
+}
+
+func (e *teiEmbedder) embedBatch(ctx context.Context, inputs []string) ([][]float32, error) {
+	body := struct {
+		Inputs []string `json:"inputs"`
+	}{Inputs: inputs}
+
+	var vectors [][]float32
+	if err := e.transport.postJSON(ctx, e.svc, "/embed", body, &vectors); err != nil {
+		return nil, err
+	}
+	if len(vectors) != len(inputs) {
+		return nil, fmt.Errorf("tei embeddings returned %d vectors for %d inputs", len(vectors), len(inputs))
+	}
+
+	return vectors, nil
+}
+
+// modelID возвращает идентификатор модели эмбеддингов. Если он не задан в конфиге,
+// один раз запрашивается у TEI через /info
+func (e *teiEmbedder) modelID(ctx context.Context) (string, error) {
+	e.modelMu.Lock()
+	defer e.modelMu.Unlock()
+
+	if e.model != "" {
+		return e.model, nil
+	}
+
+	var info struct {
+		ModelID  string `json:"model_id"`
+		ModelSHA string `json:"model_sha"`
+	}
+	if err := e.transport.getJSON(ctx, e.svc, "/info", &info); err != nil {
+		return "", err
+	}
+	if info.ModelID == "" {
+		return "", fmt.Errorf("tei info has no model_id")
+	}
+
+	e.model = info.ModelID
+	if info.ModelSHA != "" {
+		e.model += "@" + info.ModelSHA
+	}
+
+	return e.model, nil
+}
+
+// teiReranker работает с реранкером text-embeddings-inference: POST /rerank
+type teiReranker struct {
+	transport *transport
+	svc       *service
+}
+
+func (r *teiReranker) Rerank(ctx context.Context, query string, texts []string) ([]RerankResult, error) {
+	body := struct {
+		Query string   `json:"query"`
+		Texts []string `json:"texts"`
+	}{Query: query, Texts: texts}
+
+	var results []RerankResult
+	if err := r.transport.postJSON(ctx, r.svc, "/rerank", body, &results); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
@@ -0,0 +1,187 @@
+package embedding
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// transport — общий HTTP-клиент провайдеров с повторами и необязательным API-ключом
+type transport struct {
+	httpClient   *http.Client
+	apiKey       string
+	maxRetries   int
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+}
+
+// breaker размыкается после threshold подряд идущих сбоев и на cooldown отвечает
+// ошибкой сразу, не дожидаясь таймаута. После cooldown запросы снова пропускаются,
+// первый успех замыкает его, очередной сбой размыкает ещё на cooldown
+type breaker struct {
+	mu        sync.Mutex
+	threshold int
+	cooldown  time.Duration
+	failures  int
+	openUntil time.Time
+}
+
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.threshold <= 0 || b.failures < b.threshold || time.Now().After(b.openUntil)
+}
+
+func (b *breaker) success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures = 0
+}
+
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.cooldown)
+	}
+}
+
+// service — эндпоинт провайдера со своим предохранителем
+type service struct {
+	name        string
+	url         string
+	unavailable error
+	breaker     *breaker
+}
+
+type statusError struct {
+	service    string
+	status     int
+	body       string
+	retryAfter time.Duration
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("%s returned status %d: %s", e.service, e.status, e.body)
+}
+
+// isTransient — сбой, который имеет смысл повторить и который считается предохранителем:
+// сетевые ошибки, таймауты, 429 и 5xx. Отмена запроса клиентом сюда не относится
+func isTransient(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status == http.StatusTooManyRequests || se.status >= http.StatusInternalServerError
+	}
+
+	return true
+}
+
+// postJSON отправляет запрос с повторами и экспоненциальной задержкой с jitter
+func (t *transport) postJSON(ctx context.Context, svc *service, path string, body, out any) error {
+	jsonData, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal %s request: %w", svc.name, err)
+	}
+
+	backoff := t.retryBackoff
+	for attempt := 0; ; attempt++ {
+		if !svc.breaker.allow() {
+			return svc.unavailable
+		}
+
+		err := t.send(ctx, svc, http.MethodPost, path, jsonData, out)
+		if err == nil {
+			svc.breaker.success()
+			return nil
+		}
+
+		if !isTransient(ctx, err) {
+			return err
+		}
+		svc.breaker.failure()
+
+		if attempt >= t.maxRetries {
+			return err
+		}
+
+		wait := jitter(backoff)
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > wait {
+			wait = se.retryAfter
+		}
+		if t.maxBackoff > 0 {
+			wait = min(wait, t.maxBackoff)
+		}
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+
+		backoff *= 2
+	}
+}
+
+// getJSON выполняет служебный GET-запрос без повторов и без учёта предохранителем
+func (t *transport) getJSON(ctx context.Context, svc *service, path string, out any) error {
+	return t.send(ctx, svc, http.MethodGet, path, nil, out)
+}
+
+func (t *transport) send(ctx context.Context, svc *service, method, path string, jsonData []byte, out any) error {
+	url := fmt.Sprintf("%s%s", svc.url, path)
+	req, err := http.NewRequestWithContext(ctx, method, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return fmt.Errorf("failed to create %s request: %w", svc.name, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.apiKey != "" {
+		req.Header.Set("Authorization", "Bearer "+t.apiKey)
+	}
+
+	resp, err := t.httpClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to send %s request: %w", svc.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		respBody, _ := io.ReadAll(resp.Body)
+		se := &statusError{service: svc.name, status: resp.StatusCode, body: string(respBody)}
+		if seconds, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+			se.retryAfter = time.Duration(seconds) * time.Second
+		}
+		return se
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", svc.name, err)
+	}
+
+	return nil
+}
+
+// jitter возвращает случайную задержку из [d/2, d), чтобы повторы не приходили к провайдеру пачкой
+func jitter(d time.Duration) time.Duration {
+	if d <= 1 {
+		return d
+	}
+	half := d / 2
+	return half + rand.N(half)
+}
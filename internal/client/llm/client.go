@@ -1,64 +1,162 @@
 package llm
 
 import (
-	"bufio"
-	"bytes"
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
-	"strings"
+	"net/url"
+	"strconv"
+	"time"
 
 	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
 )
 
-type Client struct {
-	httpClient *http.Client
-	baseURL    string
-	model      string
+const (
+	ProviderOpenAI   = "openai"
+	ProviderOllama   = "ollama"
+	ProviderLlamaCpp = "llamacpp"
+)
+
+// protocol — формат запросов и ответов конкретного провайдера
+type protocol interface {
+	newRequest(ctx context.Context, c *Client, ep endpoint, reqBody chatRequest) (*http.Request, error)
+	decode(body io.Reader) (*ChatResponse, error)
+	// stream читает ответ до конца, emit возвращает false, если читатель больше не ждёт чанков
+	stream(body io.Reader, emit func(StreamChunk) bool) error
 }
 
-func NewClient(cfg *config.Config) *Client {
-	return &Client{
-		httpClient: &http.Client{
-			Timeout: cfg.LLM.Timeout,
-		},
-		baseURL: cfg.LLM.URL,
-		model:   cfg.LLM.Model,
+func newProtocol(provider string) (protocol, error) {
+	switch provider {
+	case "", ProviderOpenAI:
+		return openAIProtocol{}, nil
+	case ProviderOllama:
+		return ollamaProtocol{}, nil
+	case ProviderLlamaCpp:
+		return llamaCppProtocol{}, nil
+	default:
+		return nil, fmt.Errorf("unknown llm provider: %s", provider)
 	}
 }
 
-type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
+// Client обращается к LLM через протокол провайдера, повторяет запросы и переключается на резервные эндпоинты
+type Client struct {
+	httpClient   *http.Client
+	endpoints    []endpoint
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	maxBackoff   time.Duration
+}
+
+type endpoint struct {
+	url      string
+	model    string
+	protocol protocol
+}
+
+func NewClient(cfg *config.Config) (*Client, error) {
+	// общий Timeout у http.Client обрывал бы длинные стримы, поэтому ограничивается только ожидание заголовков ответа
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = cfg.LLM.Timeout
+
+	primary, err := newProtocol(cfg.LLM.Provider)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoints := []endpoint{{url: cfg.LLM.URL, model: cfg.LLM.Model, protocol: primary}}
+	for _, fb := range cfg.LLM.Fallbacks {
+		if fb.URL == "" {
+			continue
+		}
+		model := fb.Model
+		if model == "" {
+			model = cfg.LLM.Model
+		}
+		proto := primary
+		if fb.Provider != "" {
+			if proto, err = newProtocol(fb.Provider); err != nil {
+				return nil, err
+			}
+		}
+		endpoints = append(endpoints, endpoint{url: fb.URL, model: model, protocol: proto})
+	}
+
+	return &Client{
+		httpClient:   &http.Client{Transport: transport},
+		endpoints:    endpoints,
+		timeout:      cfg.LLM.Timeout,
+		maxRetries:   max(cfg.LLM.MaxRetries, 0),
+		retryBackoff: cfg.LLM.RetryBackoff,
+		maxBackoff:   cfg.LLM.MaxBackoff,
+	}, nil
 }
 
 type chatRequest struct {
-	Model       string    `json:"model"`
-	Messages    []Message `json:"messages"`
-	Stream      bool      `json:"stream"`
-	Temperature float64   `json:"temperature,omitempty"`
-	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Model         string         `json:"model"`
+	Messages      []Message      `json:"messages"`
+	Stream        bool           `json:"stream"`
+	StreamOptions *streamOptions `json:"stream_options,omitempty"`
+	Temperature   float64        `json:"temperature,omitempty"`
+	MaxTokens     int            `json:"max_tokens,omitempty"`
 }
 
-type StreamChunk struct {
-	ID      string         `json:"id"`
-	Choices []StreamChoice `json:"choices"`
+type streamOptions struct {
+	IncludeUsage bool `json:"include_usage"`
 }
 
-type StreamChoice struct {
-	Index        int          `json:"index"`
-	Delta        MessageDelta `json:"delta"`
-	FinishReason *string      `json:"finish_reason"`
+// statusError — ответ провайдера с кодом, отличным от 200
+type statusError struct {
+	status     int
+	body       string
+	retryAfter time.Duration
 }
 
-type MessageDelta struct {
-	Role             string `json:"role,omitempty"`
-	Content          string `json:"content,omitempty"`
-	ReasoningContent string `json:"reasoning_content,omitempty"`
+func (e *statusError) Error() string {
+	return fmt.Sprintf("llm returned status %d: %s", e.status, e.body)
+}
+
+func (c *Client) ChatCompletion(ctx context.Context, messages []Message, temperature float64, maxTokens int) (*ChatResponse, error) {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	resp, ep, err := c.do(ctx, chatRequest{
+		Messages:    messages,
+		Stream:      false,
+		Temperature: temperature,
+		MaxTokens:   maxTokens,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	result, err := ep.protocol.decode(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	result.Model = ep.model
+
+	for i := range result.Choices {
+		var splitter thinkSplitter
+		msg := &result.Choices[i].Message
+		content, reasoning := splitter.split(msg.Content)
+		restContent, restReasoning := splitter.flush()
+		msg.Content = content + restContent
+		msg.ReasoningContent += reasoning + restReasoning
+	}
+
+	return result, nil
 }
 
+// ChatCompletionStream повторяет запрос и переключается на резервные эндпоинты только до первого токена.
+// Обрыв уже начавшегося стрима возвращается как ошибка
 func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message, temperature float64, maxTokens int) (<-chan StreamChunk, <-chan error) {
 	chunks := make(chan StreamChunk)
 	errc := make(chan error, 1)
@@ -67,74 +165,161 @@ func (c *Client) ChatCompletionStream(ctx context.Context, messages []Message, t
 		defer close(chunks)
 		defer close(errc)
 
-		reqBody := chatRequest{
-			Model:       c.model,
+		resp, ep, err := c.do(ctx, chatRequest{
 			Messages:    messages,
 			Stream:      true,
 			Temperature: temperature,
 			MaxTokens:   maxTokens,
-		}
-
-		jsonData, err := json.Marshal(reqBody)
+		})
 		if err != nil {
-			errc <- fmt.Errorf("failed to marshal request: %w", err)
+			errc <- err
 			return
 		}
+		defer resp.Body.Close()
 
-		url := fmt.Sprintf("%s/v1/chat/completions", c.baseURL)
-		req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
-		if err != nil {
-			errc <- fmt.Errorf("failed to create request: %w", err)
-			return
+		// рассуждения в тегах <think> переносятся в ReasoningContent, как у провайдеров с отдельным полем
+		var splitter thinkSplitter
+		send := func(chunk StreamChunk) bool {
+			select {
+			case chunks <- chunk:
+				return true
+			case <-ctx.Done():
+				return false
+			}
 		}
 
-		req.Header.Set("Content-Type", "application/json")
-
-		resp, err := c.httpClient.Do(req)
+		err = ep.protocol.stream(resp.Body, func(chunk StreamChunk) bool {
+			chunk.Model = ep.model
+			for i := range chunk.Choices {
+				delta := &chunk.Choices[i].Delta
+				content, reasoning := splitter.split(delta.Content)
+				delta.Content = content
+				delta.ReasoningContent += reasoning
+			}
+			return send(chunk)
+		})
+		if err == nil && ctx.Err() == nil {
+			if content, reasoning := splitter.flush(); content != "" || reasoning != "" {
+				send(StreamChunk{Model: ep.model, Choices: []StreamChoice{{Delta: MessageDelta{Content: content, ReasoningContent: reasoning}}}})
+			}
+		}
+		if err == nil {
+			err = ctx.Err()
+		}
 		if err != nil {
-			errc <- fmt.Errorf("failed to send request: %w", err)
-			return
+			errc <- err
 		}
-		defer resp.Body.Close()
+	}()
 
-		if resp.StatusCode != http.StatusOK {
-			body, _ := io.ReadAll(resp.Body)
-			errc <- fmt.Errorf("llm returned status %d: %s", resp.StatusCode, string(body))
-			return
+	return chunks, errc
+}
+
+// do перебирает эндпоинты по порядку и возвращает первый успешный ответ и эндпоинт, который его дал.
+// К следующему эндпоинту переходит только недоступность текущего, ошибки самого запроса возвращаются сразу
+func (c *Client) do(ctx context.Context, reqBody chatRequest) (*http.Response, endpoint, error) {
+	var lastErr error
+	for i, ep := range c.endpoints {
+		if i > 0 {
+			logger.Warn(fmt.Sprintf("llm endpoint %s failed, falling back to %s (%s): %v", c.endpoints[i-1].url, ep.url, ep.model, lastErr))
 		}
 
-		scanner := bufio.NewScanner(resp.Body)
-		for scanner.Scan() {
-			line := scanner.Text()
+		resp, err := c.doWithRetry(ctx, ep, reqBody)
+		if err == nil {
+			return resp, ep, nil
+		}
+		if ctx.Err() != nil {
+			return nil, endpoint{}, ctx.Err()
+		}
+		if !isRetryable(err) {
+			return nil, endpoint{}, err
+		}
+		lastErr = err
+	}
 
-			if !strings.HasPrefix(line, "data: ") {
-				continue
-			}
+	return nil, endpoint{}, lastErr
+}
 
-			data := strings.TrimPrefix(line, "data: ")
+func (c *Client) doWithRetry(ctx context.Context, ep endpoint, reqBody chatRequest) (*http.Response, error) {
+	backoff := c.retryBackoff
+	for attempt := 0; ; attempt++ {
+		resp, err := c.send(ctx, ep, reqBody)
+		if err == nil {
+			return resp, nil
+		}
+		if attempt >= c.maxRetries || !isRetryable(err) {
+			return nil, err
+		}
 
-			if data == "[DONE]" {
-				return
-			}
+		wait := backoff
+		var se *statusError
+		if errors.As(err, &se) && se.retryAfter > wait {
+			wait = se.retryAfter
+		}
+		if c.maxBackoff > 0 {
+			wait = min(wait, c.maxBackoff)
+		}
 
-			var chunk StreamChunk
-			if err := json.Unmarshal([]byte(data), &chunk); err != nil {
-				errc <- fmt.Errorf("failed to decode chunk: %w", err)
-				return
-			}
+		logger.Debug(fmt.Sprintf("llm request to %s failed, retrying in %s: %v", ep.url, wait, err))
 
-			select {
-			case chunks <- chunk:
-			case <-ctx.Done():
-				errc <- ctx.Err()
-				return
-			}
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 
-		if err := scanner.Err(); err != nil {
-			errc <- fmt.Errorf("stream read error: %w", err)
+		backoff *= 2
+	}
+}
+
+func (c *Client) send(ctx context.Context, ep endpoint, reqBody chatRequest) (*http.Response, error) {
+	req, err := ep.protocol.newRequest(ctx, c, ep, reqBody)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send request: %w", err)
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		defer resp.Body.Close()
+		body, _ := io.ReadAll(resp.Body)
+		return nil, &statusError{
+			status:     resp.StatusCode,
+			body:       string(body),
+			retryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
 		}
-	}()
+	}
 
-	return chunks, errc
+	return resp, nil
+}
+
+// isRetryable — повторяются сетевые ошибки, 429 и 5xx. Отмена контекста и ошибки сборки запроса не повторяются
+func isRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var se *statusError
+	if errors.As(err, &se) {
+		return se.status == http.StatusTooManyRequests || se.status >= http.StatusInternalServerError
+	}
+
+	// http.Client.Do возвращает транспортные ошибки как *url.Error
+	var ue *url.Error
+	return errors.As(err, &ue)
+}
+
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if at, err := http.ParseTime(value); err == nil {
+		return time.Until(at)
+	}
+	return 0
 }
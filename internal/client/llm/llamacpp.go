@@ -0,0 +1,168 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// llamaCppProtocol — /completion сервера llama.cpp. Эндпоинт принимает готовый промпт,
+// поэтому сообщения сначала форматируются шаблоном модели через /apply-template
+type llamaCppProtocol struct{}
+
+type llamaCppRequest struct {
+	Prompt      string  `json:"prompt"`
+	Stream      bool    `json:"stream"`
+	Temperature float64 `json:"temperature,omitempty"`
+	NPredict    int     `json:"n_predict,omitempty"`
+	CachePrompt bool    `json:"cache_prompt"`
+}
+
+type llamaCppResponse struct {
+	Content         string `json:"content"`
+	Stop            bool   `json:"stop"`
+	StopType        string `json:"stop_type"`
+	Truncated       bool   `json:"truncated"`
+	TokensEvaluated int    `json:"tokens_evaluated"`
+	TokensPredicted int    `json:"tokens_predicted"`
+}
+
+func (r llamaCppResponse) usage() *Usage {
+	if !r.Stop {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.TokensEvaluated,
+		CompletionTokens: r.TokensPredicted,
+		TotalTokens:      r.TokensEvaluated + r.TokensPredicted,
+	}
+}
+
+func (llamaCppProtocol) newRequest(ctx context.Context, c *Client, ep endpoint, reqBody chatRequest) (*http.Request, error) {
+	prompt, err := applyTemplate(ctx, c.httpClient, ep.url, reqBody.Messages)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonData, err := json.Marshal(llamaCppRequest{
+		Prompt:      prompt,
+		Stream:      reqBody.Stream,
+		Temperature: reqBody.Temperature,
+		NPredict:    reqBody.MaxTokens,
+		CachePrompt: true,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/completion", ep.url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+func applyTemplate(ctx context.Context, httpClient *http.Client, baseURL string, messages []Message) (string, error) {
+	jsonData, err := json.Marshal(struct {
+		Messages []Message `json:"messages"`
+	}{Messages: messages})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal template request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/apply-template", baseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return "", fmt.Errorf("failed to create template request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send template request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return "", &statusError{status: resp.StatusCode, body: string(body)}
+	}
+
+	var result struct {
+		Prompt string `json:"prompt"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", fmt.Errorf("failed to decode template response: %w", err)
+	}
+
+	return result.Prompt, nil
+}
+
+func (llamaCppProtocol) decode(body io.Reader) (*ChatResponse, error) {
+	var resp llamaCppResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &ChatResponse{
+		Choices: []ChatChoice{{
+			Message:      MessageDelta{Role: "assistant", Content: resp.Content},
+			FinishReason: llamaCppFinishReason(resp),
+		}},
+		Usage: resp.usage(),
+	}, nil
+}
+
+func (llamaCppProtocol) stream(body io.Reader, emit func(StreamChunk) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if !strings.HasPrefix(line, "data: ") {
+			continue
+		}
+
+		var resp llamaCppResponse
+		if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &resp); err != nil {
+			return fmt.Errorf("failed to decode chunk: %w", err)
+		}
+
+		choice := StreamChoice{Delta: MessageDelta{Content: resp.Content}}
+		if resp.Stop {
+			choice.FinishReason = llamaCppFinishReason(resp)
+		}
+
+		if !emit(StreamChunk{Choices: []StreamChoice{choice}, Usage: resp.usage()}) {
+			return nil
+		}
+		if resp.Stop {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream read error: %w", err)
+	}
+
+	return nil
+}
+
+func llamaCppFinishReason(resp llamaCppResponse) *string {
+	if !resp.Stop {
+		return nil
+	}
+	if resp.StopType == "limit" || resp.Truncated {
+		return finishReason("length")
+	}
+	return finishReason("stop")
+}
@@ -0,0 +1,64 @@
+package llm
+
+import "context"
+
+// LLM — чат-модель, к которой обращаются сервисы. Реализации отличаются протоколом провайдера
+type LLM interface {
+	ChatCompletion(ctx context.Context, messages []Message, temperature float64, maxTokens int) (*ChatResponse, error)
+	ChatCompletionStream(ctx context.Context, messages []Message, temperature float64, maxTokens int) (<-chan StreamChunk, <-chan error)
+}
+
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// ChatResponse.Model — модель эндпоинта, который ответил: после перехода на резервный она отличается от основной
+type ChatResponse struct {
+	ID      string       `json:"id"`
+	Model   string       `json:"model"`
+	Choices []ChatChoice `json:"choices"`
+	Usage   *Usage       `json:"usage,omitempty"`
+}
+
+// Usage — расход токенов по данным провайдера
+type Usage struct {
+	PromptTokens     int `json:"prompt_tokens"`
+	CompletionTokens int `json:"completion_tokens"`
+	TotalTokens      int `json:"total_tokens"`
+}
+
+type ChatChoice struct {
+	Index        int          `json:"index"`
+	Message      MessageDelta `json:"message"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+// StreamChunk.Usage приходит только в последнем чанке и только если провайдер его сообщает.
+// Model — модель эндпоинта, который отдаёт стрим
+type StreamChunk struct {
+	ID      string         `json:"id"`
+	Model   string         `json:"model,omitempty"`
+	Choices []StreamChoice `json:"choices"`
+	Usage   *Usage         `json:"usage,omitempty"`
+}
+
+type StreamChoice struct {
+	Index        int          `json:"index"`
+	Delta        MessageDelta `json:"delta"`
+	FinishReason *string      `json:"finish_reason"`
+}
+
+type MessageDelta struct {
+	Role             string `json:"role,omitempty"`
+	Content          string `json:"content,omitempty"`
+	ReasoningContent string `json:"reasoning_content,omitempty"`
+}
+
+// Content возвращает текст первого варианта ответа
+func (r *ChatResponse) Content() string {
+	if r == nil || len(r.Choices) == 0 {
+		return ""
+	}
+	return r.Choices[0].Message.Content
+}
@@ -0,0 +1,149 @@
+package llm
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// ollamaProtocol — нативный /api/chat Ollama. Стрим приходит как NDJSON, по объекту на строку
+type ollamaProtocol struct{}
+
+type ollamaRequest struct {
+	Model    string        `json:"model"`
+	Messages []Message     `json:"messages"`
+	Stream   bool          `json:"stream"`
+	Options  ollamaOptions `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float64 `json:"temperature,omitempty"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaResponse struct {
+	Model   string `json:"model"`
+	Message struct {
+		Role     string `json:"role"`
+		Content  string `json:"content"`
+		Thinking string `json:"thinking"`
+	} `json:"message"`
+	Done            bool   `json:"done"`
+	DoneReason      string `json:"done_reason"`
+	PromptEvalCount int    `json:"prompt_eval_count"`
+	EvalCount       int    `json:"eval_count"`
+	Error           string `json:"error"`
+}
+
+func (r ollamaResponse) usage() *Usage {
+	if !r.Done {
+		return nil
+	}
+	return &Usage{
+		PromptTokens:     r.PromptEvalCount,
+		CompletionTokens: r.EvalCount,
+		TotalTokens:      r.PromptEvalCount + r.EvalCount,
+	}
+}
+
+func (ollamaProtocol) newRequest(ctx context.Context, _ *Client, ep endpoint, reqBody chatRequest) (*http.Request, error) {
+	jsonData, err := json.Marshal(ollamaRequest{
+		Model:    ep.model,
+		Messages: reqBody.Messages,
+		Stream:   reqBody.Stream,
+		Options: ollamaOptions{
+			Temperature: reqBody.Temperature,
+			NumPredict:  reqBody.MaxTokens,
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	url := fmt.Sprintf("%s/api/chat", ep.url)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	return req, nil
+}
+
+func (ollamaProtocol) decode(body io.Reader) (*ChatResponse, error) {
+	var resp ollamaResponse
+	if err := json.NewDecoder(body).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	if resp.Error != "" {
+		return nil, fmt.Errorf("ollama error: %s", resp.Error)
+	}
+
+	return &ChatResponse{
+		Model: resp.Model,
+		Choices: []ChatChoice{{
+			Message: MessageDelta{
+				Role:             resp.Message.Role,
+				Content:          resp.Message.Content,
+				ReasoningContent: resp.Message.Thinking,
+			},
+			FinishReason: finishReason(resp.DoneReason),
+		}},
+		Usage: resp.usage(),
+	}, nil
+}
+
+func (ollamaProtocol) stream(body io.Reader, emit func(StreamChunk) bool) error {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var resp ollamaResponse
+		if err := json.Unmarshal(line, &resp); err != nil {
+			return fmt.Errorf("failed to decode chunk: %w", err)
+		}
+		if resp.Error != "" {
+			return fmt.Errorf("ollama error: %s", resp.Error)
+		}
+
+		choice := StreamChoice{
+			Delta: MessageDelta{
+				Role:             resp.Message.Role,
+				Content:          resp.Message.Content,
+				ReasoningContent: resp.Message.Thinking,
+			},
+		}
+		if resp.Done {
+			choice.FinishReason = finishReason(resp.DoneReason)
+		}
+
+		if !emit(StreamChunk{Choices: []StreamChoice{choice}, Usage: resp.usage()}) {
+			return nil
+		}
+		if resp.Done {
+			return nil
+		}
+	}
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("stream read error: %w", err)
+	}
+
+	return nil
+}
+
+func finishReason(reason string) *string {
+	if reason == "" {
+		return nil
+	}
+	return &reason
+}
@@ -0,0 +1,76 @@
+package llm
+
+import "strings"
+
+const (
+	thinkOpenTag  = "<think>"
+	thinkCloseTag = "</think>"
+)
+
+// thinkSplitter выносит рассуждения в тегах <think>…</think> из текста ответа.
+// Тег может прийти разрезанным между чанками, поэтому возможное начало тега придерживается
+type thinkSplitter struct {
+	inThink bool
+	pending string
+	// started — ответ уже начался; до этого отбрасываются пробелы после </think>
+	started bool
+}
+
+func (t *thinkSplitter) split(delta string) (string, string) {
+	text := t.pending + delta
+	t.pending = ""
+
+	var content, reasoning strings.Builder
+	write := func(s string) {
+		if t.inThink {
+			reasoning.WriteString(s)
+			return
+		}
+		if !t.started {
+			s = strings.TrimLeft(s, " \t\r\n")
+			t.started = s != ""
+		}
+		content.WriteString(s)
+	}
+
+	for text != "" {
+		tag := thinkOpenTag
+		if t.inThink {
+			tag = thinkCloseTag
+		}
+
+		if i := strings.Index(text, tag); i >= 0 {
+			write(text[:i])
+			text = text[i+len(tag):]
+			t.inThink = !t.inThink
+			continue
+		}
+
+		keep := partialTagSuffix(text, tag)
+		write(text[:len(text)-keep])
+		t.pending = text[len(text)-keep:]
+		break
+	}
+
+	return content.String(), reasoning.String()
+}
+
+// flush возвращает придержанный хвост, когда поток закончился
+func (t *thinkSplitter) flush() (string, string) {
+	rest := t.pending
+	t.pending = ""
+	if t.inThink {
+		return "", rest
+	}
+	return rest, ""
+}
+
+// partialTagSuffix — длина самого длинного суффикса text, совпадающего с началом tag
+func partialTagSuffix(text, tag string) int {
+	for k := min(len(tag)-1, len(text)); k > 0; k-- {
+		if strings.HasSuffix(text, tag[:k]) {
+			return k
+		}
+	}
+	return 0
+}
@@ -15,6 +15,7 @@ type (
 	Config struct {
 		Server      Server
 		Database    Database
+		Storage     StorageConfig
 		MinIO       MinIOConfig
 		AuthService AuthServiceConfig
 		Limits      LimitsConfig
@@ -22,6 +23,10 @@ type (
 		LLM         LLMConfig
 		TEI         TEIConfig
 		RAG         RAGConfig
+		Indexer     IndexerConfig
+		Cleanup     CleanupConfig
+		Reconciler  ReconcilerConfig
+		Trash       TrashConfig
 	}
 
 	Server struct {
@@ -39,6 +44,12 @@ type (
 		ConnMaxLifetime time.Duration
 	}
 
+	// StorageConfig выбирает файловое хранилище: minio или local (каталог LocalPath на диске)
+	StorageConfig struct {
+		Driver    string
+		LocalPath string
+	}
+
 	MinIOConfig struct {
 		Endpoint  string
 		Bucket    string
@@ -58,6 +69,13 @@ type (
 		MaxDatasetsPerUser int
 		UploadRateLimit    int
 		AskRateLimit       int
+		TokenQuotas        map[string]TokenQuota
+	}
+
+	// TokenQuota — лимит токенов LLM на пользователя роли, 0 снимает ограничение
+	TokenQuota struct {
+		Daily   int64
+		Monthly int64
 	}
 
 	QdrantConfig struct {
@@ -68,23 +86,99 @@ type (
 	}
 
 	LLMConfig struct {
-		URL     string
-		Model   string
-		Timeout time.Duration
+		Provider     string
+		URL          string
+		Model        string
+		Timeout      time.Duration
+		MaxRetries   int
+		RetryBackoff time.Duration
+		MaxBackoff   time.Duration
+		Fallbacks    []LLMEndpoint
 	}
 
+	// LLMEndpoint — резервный эндпоинт, пустые провайдер и модель наследуются от основного
+	LLMEndpoint struct {
+		Provider string
+		URL      string
+		Model    string
+	}
+
+	// TEIConfig описывает сервисы эмбеддингов и реранкинга. Кроме TEI поддерживаются
+	// OpenAI-совместимые эмбеддинги и Cohere/Jina-совместимый реранкер, реранкер можно отключить
 	TEIConfig struct {
-		EmbeddingsURL string
-		RerankerURL   string
-		Timeout       time.Duration
+		EmbeddingProvider string
+		EmbeddingsURL     string
+		EmbeddingApiKey   string
+		RerankerProvider  string
+		RerankerURL       string
+		RerankerApiKey    string
+		RerankerModel     string
+		Timeout           time.Duration
+		EmbeddingModel    string
+		EmbeddingCache    bool
+		EmbeddingCacheTTL time.Duration
+		MaxConcurrency    int
+		MaxRetries        int
+		RetryBackoff      time.Duration
+		MaxBackoff        time.Duration
+		BreakerThreshold  int
+		BreakerCooldown   time.Duration
 	}
 
 	RAGConfig struct {
-		SearchTopK     int
-		RerankTopN     int
-		LLMTemperature float64
-		LLMMaxTokens   int
-		VectorSize     int
+		SearchTopK         int
+		RerankTopN         int
+		LLMTemperature     float64
+		LLMMaxTokens       int
+		VectorSize         int
+		ChunkMaxTokens     int
+		ChunkOverlapTokens int
+		DenseWeight        float64
+		SparseWeight       float64
+		RRFK               int
+		HistoryMaxTurns    int
+		HistoryMaxTokens   int
+		GroundingEnabled   bool
+		GroundingThreshold float64
+		AnswerCache        AnswerCacheConfig
+	}
+
+	AnswerCacheConfig struct {
+		Enabled   bool
+		Threshold float64
+		TTL       time.Duration
+	}
+
+	IndexerConfig struct {
+		Workers            int
+		MaxAttempts        int
+		PollInterval       time.Duration
+		RetryBackoff       time.Duration
+		MaxBackoff         time.Duration
+		JobTimeout         time.Duration
+		StatusPollInterval time.Duration
+	}
+
+	CleanupConfig struct {
+		PollInterval time.Duration
+		RetryBackoff time.Duration
+		MaxBackoff   time.Duration
+		TaskTimeout  time.Duration
+	}
+
+	// ReconcilerConfig — периодическая сверка MySQL, MinIO и Qdrant.
+	// Без Apply плановые запуски только пишут отчёт в лог
+	ReconcilerConfig struct {
+		Enabled     bool
+		Interval    time.Duration
+		Apply       bool
+		GracePeriod time.Duration
+	}
+
+	// TrashConfig — срок хранения удалённых датасетов, тем и чатов до окончательного удаления
+	TrashConfig struct {
+		Retention     time.Duration
+		PurgeInterval time.Duration
 	}
 )
 
@@ -110,9 +204,25 @@ func Init() (*Config, error) {
 		return nil, fmt.Errorf("failed to set environment variables: %w", err)
 	}
 
+	setDefaults(&cfg)
+
 	return &cfg, nil
 }
 
+// setDefaults подставляет значения, без которых сервис не может работать, если они не заданы в main.yml
+func setDefaults(cfg *Config) {
+	// интервалы уходят в time.NewTicker, который паникует на нуле
+	if cfg.Indexer.PollInterval <= 0 {
+		cfg.Indexer.PollInterval = 5 * time.Second
+	}
+	if cfg.Indexer.StatusPollInterval <= 0 {
+		cfg.Indexer.StatusPollInterval = time.Second
+	}
+	if cfg.Cleanup.PollInterval <= 0 {
+		cfg.Cleanup.PollInterval = 30 * time.Second
+	}
+}
+
 func parseConfigFile(folder string) error {
 	viper.AddConfigPath(folder)
 	viper.SetConfigName("main")
@@ -127,18 +237,28 @@ func setFromEnv(cfg *Config) error {
 		return errors.New("DB_DSN environment variable is required")
 	}
 
+	if driver := os.Getenv("STORAGE_DRIVER"); driver != "" {
+		cfg.Storage.Driver = driver
+	}
+	if localPath := os.Getenv("STORAGE_LOCAL_PATH"); localPath != "" {
+		cfg.Storage.LocalPath = localPath
+	}
+
 	cfg.MinIO.Endpoint = os.Getenv("MINIO_ENDPOINT")
 	cfg.MinIO.AccessKey = os.Getenv("MINIO_ACCESS_KEY")
 	cfg.MinIO.SecretKey = os.Getenv("MINIO_SECRET_KEY")
 
-	if cfg.MinIO.Endpoint == "" {
-		return errors.New("MINIO_ENDPOINT environment variable is required")
-	}
-	if cfg.MinIO.AccessKey == "" {
-		return errors.New("MINIO_ACCESS_KEY environment variable is required")
-	}
-	if cfg.MinIO.SecretKey == "" {
-		return errors.New("MINIO_SECRET_KEY environment variable is required")
+	// с локальным хранилищем MinIO не нужен
+	if cfg.Storage.Driver != "local" {
+		if cfg.MinIO.Endpoint == "" {
+			return errors.New("MINIO_ENDPOINT environment variable is required")
+		}
+		if cfg.MinIO.AccessKey == "" {
+			return errors.New("MINIO_ACCESS_KEY environment variable is required")
+		}
+		if cfg.MinIO.SecretKey == "" {
+			return errors.New("MINIO_SECRET_KEY environment variable is required")
+		}
 	}
 
 	cfg.AuthService.URL = os.Getenv("AUTH_SERVICE_URL")
@@ -168,6 +288,10 @@ func setFromEnv(cfg *Config) error {
 		cfg.Qdrant.Collection = "datasets"
 	}
 
+	if provider := os.Getenv("LLM_PROVIDER"); provider != "" {
+		cfg.LLM.Provider = provider
+	}
+
 	cfg.LLM.URL = os.Getenv("LLM_URL")
 	if cfg.LLM.URL == "" {
 		cfg.LLM.URL = "http://localhost:11434"
@@ -178,6 +302,17 @@ func setFromEnv(cfg *Config) error {
 		cfg.LLM.Model = "default"
 	}
 
+	if provider := os.Getenv("EMBEDDING_PROVIDER"); provider != "" {
+		cfg.TEI.EmbeddingProvider = provider
+	}
+
+	if provider := os.Getenv("RERANKER_PROVIDER"); provider != "" {
+		cfg.TEI.RerankerProvider = provider
+	}
+
+	cfg.TEI.EmbeddingApiKey = os.Getenv("EMBEDDING_API_KEY")
+	cfg.TEI.RerankerApiKey = os.Getenv("RERANKER_API_KEY")
+
 	cfg.TEI.EmbeddingsURL = os.Getenv("TEI_EMBEDDINGS_URL")
 	if cfg.TEI.EmbeddingsURL == "" {
 		cfg.TEI.EmbeddingsURL = "http://localhost:6500"
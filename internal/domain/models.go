@@ -9,18 +9,65 @@ type User struct {
 }
 
 type Dataset struct {
-	ID           string     `json:"id" db:"id"`
-	UserID       string     `json:"user_id" db:"user_id"`
-	Author       string     `json:"author,omitempty" db:"author"`
-	Title        string     `json:"title" db:"title"`
-	FilePath     string     `json:"file_path" db:"file_path"`
-	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time  `json:"updated_at" db:"updated_at"`
-	IndexedAt    *time.Time `json:"indexed_at" db:"indexed_at"`
-	TopicID      *string    `json:"topic_id,omitempty" db:"topic_id"`
-	AssignmentID *string    `json:"assignment_id,omitempty" db:"assignment_id"`
-	Tag          *string    `json:"tag,omitempty" db:"tag"`
-	Content      string     `json:"content,omitempty"`
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	Author         string     `json:"author,omitempty" db:"author"`
+	Title          string     `json:"title" db:"title"`
+	FilePath       string     `json:"file_path" db:"file_path"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+	IndexedAt      *time.Time `json:"indexed_at" db:"indexed_at"`
+	TopicID        *string    `json:"topic_id,omitempty" db:"topic_id"`
+	AssignmentID   *string    `json:"assignment_id,omitempty" db:"assignment_id"`
+	Tag            *string    `json:"tag,omitempty" db:"tag"`
+	CurrentVersion int        `json:"current_version" db:"current_version"`
+	Content        string     `json:"content,omitempty"`
+}
+
+type DatasetVersion struct {
+	ID        string     `json:"id" db:"id"`
+	DatasetID string     `json:"dataset_id" db:"dataset_id"`
+	Version   int        `json:"version" db:"version"`
+	FilePath  string     `json:"file_path" db:"file_path"`
+	Size      int64      `json:"size" db:"size"`
+	CreatedBy string     `json:"created_by" db:"created_by"`
+	CreatedAt time.Time  `json:"created_at" db:"created_at"`
+	IndexedAt *time.Time `json:"indexed_at" db:"indexed_at"`
+	IsCurrent bool       `json:"is_current" db:"-"`
+}
+
+type DatasetVersionListResponse struct {
+	DatasetID      string           `json:"dataset_id"`
+	CurrentVersion int              `json:"current_version"`
+	Versions       []DatasetVersion `json:"versions"`
+}
+
+type DatasetVersionResponse struct {
+	DatasetID string     `json:"dataset_id"`
+	Version   int        `json:"version"`
+	Content   string     `json:"content"`
+	Size      int64      `json:"size"`
+	IsCurrent bool       `json:"is_current"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	IndexedAt *time.Time `json:"indexed_at,omitempty"`
+}
+
+type SectionDiff struct {
+	Title        string `json:"title"`
+	Status       string `json:"status"`
+	LinesAdded   int    `json:"lines_added"`
+	LinesRemoved int    `json:"lines_removed"`
+}
+
+type DatasetDiffResponse struct {
+	DatasetID    string        `json:"dataset_id"`
+	FromVersion  int           `json:"from_version"`
+	ToVersion    int           `json:"to_version"`
+	LinesAdded   int           `json:"lines_added"`
+	LinesRemoved int           `json:"lines_removed"`
+	Unified      string        `json:"unified"`
+	Sections     []SectionDiff `json:"sections"`
 }
 
 type DatasetListResponse struct {
@@ -31,7 +78,24 @@ type DatasetListResponse struct {
 }
 
 type AskRequest struct {
+	Question string     `json:"question" binding:"required"`
+	Version  *int       `json:"version,omitempty" binding:"omitempty,min=1"`
+	History  []ChatTurn `json:"history,omitempty" binding:"omitempty,max=50,dive"`
+	ChatID   *string    `json:"chat_id,omitempty"`
+}
+
+type MultiAskRequest struct {
+	Question   string     `json:"question" binding:"required"`
+	DatasetIDs []string   `json:"dataset_ids,omitempty" binding:"omitempty,max=50"`
+	Tag        *string    `json:"tag,omitempty"`
+	TopicID    *string    `json:"topic_id,omitempty"`
+	History    []ChatTurn `json:"history,omitempty" binding:"omitempty,max=50,dive"`
+}
+
+// ChatTurn — предыдущий вопрос и ответ диалога
+type ChatTurn struct {
 	Question string `json:"question" binding:"required"`
+	Answer   string `json:"answer" binding:"required"`
 }
 
 type AskResponse struct {
@@ -40,17 +104,57 @@ type AskResponse struct {
 }
 
 type AskEvent struct {
-	Type      string     `json:"type"`
-	Delta     string     `json:"delta,omitempty"`
-	Citations []Citation `json:"citations,omitempty"`
-	Error     string     `json:"error,omitempty"`
+	Type      string           `json:"type"`
+	Delta     string           `json:"delta,omitempty"`
+	Citations []Citation       `json:"citations,omitempty"`
+	Grounding *GroundingReport `json:"grounding,omitempty"`
+	Error     string           `json:"error,omitempty"`
+	// Cached — ответ взят из кэша похожих вопросов без обращения к LLM
+	Cached bool `json:"cached,omitempty"`
+}
+
+type CachedAnswer struct {
+	DatasetID string
+	Version   int
+	Question  string
+	Answer    string
+	Citations []Citation
+	Score     float32
+	CreatedAt time.Time
+}
+
+type GroundingClaim struct {
+	Text string `json:"text"`
+	// Marker — номер фрагмента с наибольшей оценкой для утверждения
+	Marker    int     `json:"marker"`
+	Score     float64 `json:"score"`
+	Supported bool    `json:"supported"`
+}
+
+type GroundingReport struct {
+	Claims         []GroundingClaim `json:"claims"`
+	SupportedRatio float64          `json:"supported_ratio"`
+	Threshold      float64          `json:"threshold"`
+	Warning        string           `json:"warning,omitempty"`
 }
 
 type Citation struct {
+	// Marker — номер фрагмента в контексте, на который ответ ссылается маркером [n]
+	Marker           int     `json:"marker"`
+	Cited            bool    `json:"cited"`
 	ChunkID          int     `json:"chunk_id"`
 	Score            float64 `json:"score"`
 	OriginalScore    float64 `json:"original_score,omitempty"`
 	ScoreImprovement float64 `json:"score_improvement,omitempty"`
+	DatasetID        string  `json:"dataset_id,omitempty"`
+	DatasetTitle     string  `json:"dataset_title,omitempty"`
+	Author           string  `json:"author,omitempty"`
+	SectionTitle     string  `json:"section_title,omitempty"`
+	Text             string  `json:"text"`
+	// StartOffset и EndOffset — границы фрагмента в нормализованном markdown версии, в символах.
+	// У чанков, проиндексированных до появления смещений, оба равны нулю
+	StartOffset int `json:"start_offset"`
+	EndOffset   int `json:"end_offset"`
 }
 
 type CreateDatasetRequest struct {
@@ -64,31 +168,182 @@ type UpdateDatasetRequest struct {
 }
 
 type DatasetResponse struct {
-	ID        string     `json:"id"`
-	Title     string     `json:"title"`
-	Content   string     `json:"content"`
-	Author    string     `json:"author"`
-	UserID    string     `json:"user_id"`
-	CreatedAt time.Time  `json:"created_at"`
-	UpdatedAt time.Time  `json:"updated_at"`
-	IndexedAt *time.Time `json:"indexed_at,omitempty"`
-	Tag       *string    `json:"tag,omitempty"`
+	ID             string     `json:"id"`
+	Title          string     `json:"title"`
+	Content        string     `json:"content"`
+	Author         string     `json:"author"`
+	UserID         string     `json:"user_id"`
+	CreatedAt      time.Time  `json:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at"`
+	IndexedAt      *time.Time `json:"indexed_at,omitempty"`
+	Tag            *string    `json:"tag,omitempty"`
+	CurrentVersion int        `json:"current_version"`
+}
+
+const (
+	IndexJobQueued    = "queued"
+	IndexJobRunning   = "running"
+	IndexJobFailed    = "failed"
+	IndexJobSucceeded = "succeeded"
+
+	IndexStatusNotIndexed = "not_indexed"
+)
+
+type IndexJob struct {
+	ID             string     `json:"id" db:"id"`
+	DatasetID      string     `json:"dataset_id" db:"dataset_id"`
+	Version        int        `json:"version" db:"version"`
+	Status         string     `json:"status" db:"status"`
+	Attempts       int        `json:"attempts" db:"attempts"`
+	MaxAttempts    int        `json:"max_attempts" db:"max_attempts"`
+	ChunksTotal    int        `json:"chunks_total" db:"chunks_total"`
+	ChunksEmbedded int        `json:"chunks_embedded" db:"chunks_embedded"`
+	LastError      *string    `json:"last_error,omitempty" db:"last_error"`
+	RunAfter       time.Time  `json:"run_after" db:"run_after"`
+	StartedAt      *time.Time `json:"started_at,omitempty" db:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty" db:"finished_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	CleanupFile        = "file"
+	CleanupVectors     = "vectors"
+	CleanupAnswerCache = "answer_cache"
+)
+
+// CleanupTask — отложенная очистка хранилища после удаления датасета.
+// Задача создаётся в одной транзакции с удалением строки и повторяется, пока не выполнится.
+// Target — путь объекта для CleanupFile, для остальных видов пуст
+type CleanupTask struct {
+	ID        string    `json:"id" db:"id"`
+	DatasetID string    `json:"dataset_id" db:"dataset_id"`
+	Kind      string    `json:"kind" db:"kind"`
+	Target    string    `json:"target" db:"target"`
+	Attempts  int       `json:"attempts" db:"attempts"`
+	LastError *string   `json:"last_error,omitempty" db:"last_error"`
+	RunAfter  time.Time `json:"run_after" db:"run_after"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+const (
+	TrashDataset = "dataset"
+	TrashTopic   = "topic"
+	TrashChat    = "chat"
+)
+
+// TrashItem — удалённая запись, которую ещё можно восстановить до PurgeAt
+type TrashItem struct {
+	Type      string    `json:"type" db:"-"`
+	ID        string    `json:"id" db:"id"`
+	Title     string    `json:"title" db:"title"`
+	Owner     string    `json:"owner" db:"owner"`
+	DeletedAt time.Time `json:"deleted_at" db:"deleted_at"`
+	DeletedBy string    `json:"deleted_by" db:"deleted_by"`
+	PurgeAt   time.Time `json:"purge_at" db:"-"`
+}
+
+type TrashListResponse struct {
+	Items []TrashItem `json:"items"`
+	Total int         `json:"total"`
+	Page  int         `json:"page"`
+	Limit int         `json:"limit"`
 }
 
-type IndexResponse struct {
-	Success bool   `json:"success"`
-	Chunks  int    `json:"chunks"`
-	Message string `json:"message,omitempty"`
+// FileInfo — объект в файловом хранилище
+type FileInfo struct {
+	Path        string    `json:"path"`
+	Size        int64     `json:"size"`
+	ModifiedAt  time.Time `json:"modified_at"`
+	ContentType string    `json:"content_type,omitempty"`
+	ETag        string    `json:"etag,omitempty"`
+}
+
+const (
+	ReconcileDryRun = "dry_run"
+	ReconcileApply  = "apply"
+)
+
+const (
+	ReconcileRunning   = "running"
+	ReconcileCompleted = "completed"
+	ReconcileFailed    = "failed"
+)
+
+// ReconcileRun — запуск сверки. Report заполняется, когда сверка завершилась
+type ReconcileRun struct {
+	ID         string           `json:"id"`
+	Mode       string           `json:"mode"`
+	Status     string           `json:"status"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+	Error      string           `json:"error,omitempty"`
+	Report     *ReconcileReport `json:"report,omitempty"`
+}
+
+// ReconcileVersion — версия датасета, помеченная проиндексированной, но без точек в Qdrant
+type ReconcileVersion struct {
+	DatasetID string `json:"dataset_id"`
+	Version   int    `json:"version"`
+}
+
+// ReconcileReport — расхождения между MySQL, MinIO и Qdrant. В режиме apply Repaired
+// считает исправленные записи, MissingObjects только сообщаются: восстановить их не из чего
+type ReconcileReport struct {
+	Mode           string             `json:"mode"`
+	StartedAt      time.Time          `json:"started_at"`
+	FinishedAt     time.Time          `json:"finished_at"`
+	Datasets       int                `json:"datasets"`
+	Objects        int                `json:"objects"`
+	OrphanObjects  []string           `json:"orphan_objects"`
+	OrphanVectors  []string           `json:"orphan_vectors"`
+	MissingVectors []ReconcileVersion `json:"missing_vectors"`
+	MissingObjects []string           `json:"missing_objects"`
+	Repaired       int                `json:"repaired"`
+	Errors         []string           `json:"errors,omitempty"`
+}
+
+// LLMUsage — расход токенов одной модели в запросе к /ask. Estimated означает,
+// что провайдер не сообщил usage и токены посчитаны оценкой
+type LLMUsage struct {
+	ID               string    `json:"id" db:"id"`
+	UserID           string    `json:"user_id" db:"user_id"`
+	Role             string    `json:"role" db:"role"`
+	DatasetID        *string   `json:"dataset_id,omitempty" db:"dataset_id"`
+	Model            *string   `json:"model,omitempty" db:"model"`
+	PromptTokens     int       `json:"prompt_tokens" db:"prompt_tokens"`
+	CompletionTokens int       `json:"completion_tokens" db:"completion_tokens"`
+	TotalTokens      int       `json:"total_tokens" db:"total_tokens"`
+	Estimated        bool      `json:"estimated" db:"estimated"`
+	LatencyMs        int64     `json:"latency_ms" db:"latency_ms"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+}
+
+type IndexStatusResponse struct {
+	DatasetID      string     `json:"dataset_id"`
+	Version        int        `json:"version"`
+	JobID          string     `json:"job_id,omitempty"`
+	Status         string     `json:"status"`
+	ChunksEmbedded int        `json:"chunks_embedded"`
+	ChunksTotal    int        `json:"chunks_total"`
+	Attempts       int        `json:"attempts"`
+	MaxAttempts    int        `json:"max_attempts,omitempty"`
+	LastError      *string    `json:"last_error,omitempty"`
+	RunAfter       *time.Time `json:"run_after,omitempty"`
+	IndexedAt      *time.Time `json:"indexed_at,omitempty"`
+	UpdatedAt      *time.Time `json:"updated_at,omitempty"`
 }
 
 type Topic struct {
-	ID          string    `json:"id" db:"id"`
-	Title       string    `json:"title" db:"title"`
-	Description string    `json:"description" db:"description"`
-	CreatedBy   string    `json:"created_by" db:"created_by"`
-	CreatedByID string    `json:"created_by_id" db:"created_by_id"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at" db:"updated_at"`
+	ID               string    `json:"id" db:"id"`
+	Title            string    `json:"title" db:"title"`
+	Description      string    `json:"description" db:"description"`
+	CreatedBy        string    `json:"created_by" db:"created_by"`
+	CreatedByID      string    `json:"created_by_id" db:"created_by_id"`
+	ChunkingStrategy string    `json:"chunking_strategy" db:"chunking_strategy"`
+	CreatedAt        time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at" db:"updated_at"`
 }
 
 type TopicAssignment struct {
@@ -107,9 +362,14 @@ type StudentInfo struct {
 }
 
 type CreateTopicRequest struct {
-	Title       string        `json:"title" binding:"required"`
-	Description string        `json:"description"`
-	Students    []StudentInfo `json:"students" binding:"required,min=1,dive"`
+	Title            string        `json:"title" binding:"required"`
+	Description      string        `json:"description"`
+	ChunkingStrategy string        `json:"chunking_strategy" binding:"omitempty,oneof=header fixed_window semantic_paragraph code_aware"`
+	Students         []StudentInfo `json:"students" binding:"required,min=1,dive"`
+}
+
+type UpdateTopicChunkingRequest struct {
+	ChunkingStrategy string `json:"chunking_strategy" binding:"required,oneof=header fixed_window semantic_paragraph code_aware"`
 }
 
 type AddStudentsRequest struct {
@@ -236,15 +496,52 @@ type SetTagRequest struct {
 }
 
 type ChunkData struct {
-	Index int
-	Text  string
+	Index        int
+	SectionTitle string
+	Text         string
+	StartOffset  int
+	EndOffset    int
+	ContentHash  string
 }
 
-type SearchHit struct {
-	Score     float32
+type SearchTarget struct {
 	DatasetID string
 	Version   int
-	ChunkID   int
-	Title     string
-	Text      string
+}
+
+type SearchHit struct {
+	Score        float32
+	DatasetID    string
+	Version      int
+	ChunkID      int
+	Title        string
+	SectionTitle string
+	Text         string
+	StartOffset  int
+	EndOffset    int
+}
+
+type ChunkInfo struct {
+	ChunkID      int    `json:"chunk_id"`
+	SectionTitle string `json:"section_title"`
+	Size         int    `json:"size"`
+	Tokens       int    `json:"tokens"`
+	Text         string `json:"text"`
+	StartOffset  int    `json:"start_offset"`
+	EndOffset    int    `json:"end_offset"`
+}
+
+type ChunkStatistics struct {
+	TotalChunks int      `json:"total_chunks"`
+	AvgSize     int      `json:"avg_size"`
+	MinSize     int      `json:"min_size"`
+	MaxSize     int      `json:"max_size"`
+	Sections    []string `json:"sections"`
+}
+
+type DatasetChunksResponse struct {
+	DatasetID  string          `json:"dataset_id"`
+	Version    int             `json:"version"`
+	Chunks     []ChunkInfo     `json:"chunks"`
+	Statistics ChunkStatistics `json:"statistics"`
 }
@@ -0,0 +1,50 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+// reconcileStorage запускает сверку хранилищ в фоне. По умолчанию dry run, mode=apply исправляет расхождения.
+// Отчёт отдаёт getReconcileStatus
+func (h *Handler) reconcileStorage(c *gin.Context) {
+	mode := c.DefaultQuery("mode", domain.ReconcileDryRun)
+	if mode != domain.ReconcileDryRun && mode != domain.ReconcileApply {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "mode must be dry_run or apply",
+		})
+		return
+	}
+
+	run, err := h.services.Reconcile.Launch(mode == domain.ReconcileApply)
+	if err != nil {
+		if err.Error() == "reconciliation already running" {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+				"run":   h.services.Reconcile.LastRun(),
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// getReconcileStatus возвращает текущую или последнюю сверку вместе с отчётом
+func (h *Handler) getReconcileStatus(c *gin.Context) {
+	run := h.services.Reconcile.LastRun()
+	if run == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "no reconciliation runs yet",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"path"
 	"strconv"
 
 	"github.com/anton1ks96/college-core-api/internal/domain"
@@ -75,7 +76,7 @@ func (h *Handler) createDataset(c *gin.Context) {
 	}
 	defer src.Close()
 
-	dataset, err := h.services.Dataset.Create(c.Request.Context(), userID.(string), username.(string), title, assignmentID, src)
+	dataset, err := h.services.Dataset.Create(c.Request.Context(), userID.(string), username.(string), title, assignmentID, src, file.Size)
 	if err != nil {
 		if err.Error() == "assignment not found" {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -175,6 +176,58 @@ func (h *Handler) getDataset(c *gin.Context) {
 	c.JSON(http.StatusOK, dataset)
 }
 
+// getDatasetRaw отдаёт исходный markdown-файл без буферизации, с поддержкой Range и условных запросов
+func (h *Handler) getDatasetRaw(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	file, info, err := h.services.Dataset.OpenFile(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "dataset not found",
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	defer file.Close()
+
+	if info.ContentType != "" {
+		c.Header("Content-Type", info.ContentType)
+	}
+	if info.ETag != "" {
+		c.Header("ETag", info.ETag)
+	}
+	c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", path.Base(info.Path)))
+
+	// ServeContent выставляет Content-Length, обрабатывает Range, If-Range и If-None-Match
+	http.ServeContent(c.Writer, c.Request, path.Base(info.Path), info.ModifiedAt, file)
+}
+
 func (h *Handler) updateDataset(c *gin.Context) {
 	datasetID := c.Param("id")
 	if datasetID == "" {
@@ -185,6 +238,7 @@ func (h *Handler) updateDataset(c *gin.Context) {
 	}
 
 	userID, _ := c.Get("user_id")
+	username, _ := c.Get("username")
 
 	var req domain.UpdateDatasetRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
@@ -198,6 +252,7 @@ func (h *Handler) updateDataset(c *gin.Context) {
 		c.Request.Context(),
 		datasetID,
 		userID.(string),
+		username.(string),
 		req.Title,
 		&req.Content,
 	)
@@ -222,10 +277,11 @@ func (h *Handler) updateDataset(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"id":         dataset.ID,
-		"title":      dataset.Title,
-		"updated_at": dataset.UpdatedAt,
-		"message":    "Dataset updated successfully",
+		"id":              dataset.ID,
+		"title":           dataset.Title,
+		"current_version": dataset.CurrentVersion,
+		"updated_at":      dataset.UpdatedAt,
+		"message":         "Dataset updated successfully",
 	})
 }
 
@@ -298,7 +354,7 @@ func (h *Handler) askQuestion(c *gin.Context) {
 		datasetID,
 		userID.(string),
 		role.(string),
-		req.Question,
+		req,
 	)
 
 	if err != nil {
@@ -314,6 +370,24 @@ func (h *Handler) askQuestion(c *gin.Context) {
 			})
 			return
 		}
+		if err.Error() == "daily token quota exceeded" || err.Error() == "monthly token quota exceeded" {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "version not found" || err.Error() == "chat not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "chat does not belong to this dataset" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "chat does not belong to this dataset",
+			})
+			return
+		}
 		if err.Error() == "dataset is not indexed yet, please wait" {
 			c.JSON(http.StatusPreconditionFailed, gin.H{
 				"error": "dataset is not indexed yet, please wait",
@@ -340,6 +414,353 @@ func (h *Handler) askQuestion(c *gin.Context) {
 	})
 }
 
+func (h *Handler) askMultipleDatasets(c *gin.Context) {
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	var req domain.MultiAskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	events, err := h.services.Dataset.AskMultiple(
+		c.Request.Context(),
+		userID.(string),
+		role.(string),
+		req,
+	)
+
+	if err != nil {
+		if err.Error() == "dataset_ids, tag or topic_id is required" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "dataset_ids, tag or topic_id is required",
+			})
+			return
+		}
+		if err.Error() == "too many datasets" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "tag or topic matches more than 50 datasets, list dataset_ids instead",
+			})
+			return
+		}
+		if err.Error() == "dataset not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "dataset not found",
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		if err.Error() == "daily token quota exceeded" || err.Error() == "monthly token quota exceeded" {
+			c.JSON(http.StatusTooManyRequests, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "no indexed datasets available" {
+			c.JSON(http.StatusPreconditionFailed, gin.H{
+				"error": "no indexed datasets available",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	c.Stream(func(w io.Writer) bool {
+		event, ok := <-events
+		if !ok {
+			return false
+		}
+		c.SSEvent("message", event)
+		return true
+	})
+}
+
+func (h *Handler) getDatasetVersions(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	versions, err := h.services.Dataset.GetVersions(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "dataset not found",
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, versions)
+}
+
+func (h *Handler) getDatasetVersion(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid version",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	response, err := h.services.Dataset.GetVersion(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+		version,
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" || err.Error() == "version not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) setCurrentVersion(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.Param("version"))
+	if err != nil || version < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid version",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	dataset, err := h.services.Dataset.SetCurrentVersion(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		version,
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" || err.Error() == "version not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "access denied: only owner can change current version" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "only owner can change current version",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":              dataset.ID,
+		"current_version": dataset.CurrentVersion,
+		"indexed_at":      dataset.IndexedAt,
+		"message":         "Current version updated successfully",
+	})
+}
+
+func (h *Handler) getDatasetChunks(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	version, err := strconv.Atoi(c.DefaultQuery("version", "0"))
+	if err != nil || version < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid version",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	chunks, err := h.services.Dataset.GetChunks(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+		version,
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" || err.Error() == "version not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, chunks)
+}
+
+func (h *Handler) getDatasetDiff(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	from, err := strconv.Atoi(c.DefaultQuery("from", "0"))
+	if err != nil || from < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid from version",
+		})
+		return
+	}
+
+	to, err := strconv.Atoi(c.DefaultQuery("to", "0"))
+	if err != nil || to < 0 {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid to version",
+		})
+		return
+	}
+
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "markdown" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "format must be json or markdown",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	var diff *domain.DatasetDiffResponse
+	var content []byte
+	if format == "markdown" {
+		content, err = h.services.Dataset.GetDiffMarkdown(c.Request.Context(), datasetID, userID.(string), role.(string), from, to)
+	} else {
+		diff, err = h.services.Dataset.GetDiff(c.Request.Context(), datasetID, userID.(string), role.(string), from, to)
+	}
+
+	if err != nil {
+		if err.Error() == "dataset not found" || err.Error() == "version not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		if err.Error() == "no previous version to compare with" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "no previous version to compare with",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if format == "markdown" {
+		c.Data(http.StatusOK, "text/markdown; charset=utf-8", content)
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
 func (h *Handler) reindexDataset(c *gin.Context) {
 	datasetID := c.Param("id")
 	if datasetID == "" {
@@ -351,7 +772,7 @@ func (h *Handler) reindexDataset(c *gin.Context) {
 
 	userID, _ := c.Get("user_id")
 
-	response, err := h.services.Dataset.Reindex(
+	job, err := h.services.Dataset.Reindex(
 		c.Request.Context(),
 		datasetID,
 		userID.(string),
@@ -376,7 +797,104 @@ func (h *Handler) reindexDataset(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, response)
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":  job.ID,
+		"status":  job.Status,
+		"message": "Dataset queued for indexing",
+	})
+}
+
+func (h *Handler) getIndexStatus(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	status, err := h.services.Dataset.GetIndexStatus(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "dataset not found",
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, status)
+}
+
+func (h *Handler) streamIndexStatus(c *gin.Context) {
+	datasetID := c.Param("id")
+	if datasetID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "dataset id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	updates, err := h.services.Dataset.WatchIndexStatus(
+		c.Request.Context(),
+		datasetID,
+		userID.(string),
+		role.(string),
+	)
+
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "dataset not found",
+			})
+			return
+		}
+		if err.Error() == "access denied" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "access denied",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.Writer.Header().Set("Content-Type", "text/event-stream")
+	c.Writer.Header().Set("Cache-Control", "no-cache")
+	c.Writer.Header().Set("Connection", "keep-alive")
+
+	c.Stream(func(w io.Writer) bool {
+		status, ok := <-updates
+		if !ok {
+			return false
+		}
+		c.SSEvent("message", status)
+		return true
+	})
 }
 
 func (h *Handler) setDatasetTag(c *gin.Context) {
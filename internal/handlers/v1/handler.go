@@ -26,12 +26,22 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 
 		datasets.GET("", h.getDatasets)
 		datasets.GET("/search", httpmw.RequireRole("teacher", "admin"), h.searchDatasetsByTag)
+		datasets.POST("/ask", httpmw.RateLimitMiddleware(h.cfg.Limits.AskRateLimit), h.askMultipleDatasets)
 		datasets.GET("/:id", h.getDataset)
+		datasets.GET("/:id/raw", h.getDatasetRaw)
 		datasets.PUT("/:id", h.updateDataset)
 		datasets.DELETE("/:id", httpmw.RequireRole("teacher", "admin"), h.deleteDataset)
 
+		datasets.GET("/:id/versions", h.getDatasetVersions)
+		datasets.GET("/:id/versions/:version", h.getDatasetVersion)
+		datasets.PUT("/:id/versions/:version/current", h.setCurrentVersion)
+		datasets.GET("/:id/diff", h.getDatasetDiff)
+		datasets.GET("/:id/chunks", h.getDatasetChunks)
+
 		datasets.POST("/:id/ask", httpmw.RateLimitMiddleware(h.cfg.Limits.AskRateLimit), h.askQuestion)
 		datasets.POST("/:id/reindex", h.reindexDataset)
+		datasets.GET("/:id/index-status", h.getIndexStatus)
+		datasets.GET("/:id/index-status/stream", h.streamIndexStatus)
 
 		datasets.PUT("/:id/tag", httpmw.RequireRole("teacher", "admin"), h.setDatasetTag)
 		datasets.DELETE("/:id/tag", httpmw.RequireRole("teacher", "admin"), h.deleteDatasetTag)
@@ -62,6 +72,8 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 		topics.POST("", httpmw.RequireRole("teacher", "admin"), h.createTopic)
 		topics.GET("", httpmw.RequireRole("teacher", "admin"), h.getMyTopics)
 		topics.GET("/all", httpmw.RequireRole("admin"), h.getAllTopics)
+		topics.PUT("/:id/chunking", httpmw.RequireRole("teacher", "admin"), h.updateTopicChunking)
+		topics.DELETE("/:id", httpmw.RequireRole("teacher", "admin"), h.deleteTopic)
 		topics.POST("/:id/students", httpmw.RequireRole("teacher", "admin"), h.addStudentsToTopic)
 		topics.GET("/:id/students", httpmw.RequireRole("teacher", "admin"), h.getTopicStudents)
 		topics.DELETE("/:id/students/:student_id", httpmw.RequireRole("teacher", "admin"), h.removeStudentFromTopic)
@@ -69,6 +81,18 @@ func (h *Handler) Init(api *gin.RouterGroup) {
 		topics.GET("/assigned", h.getAssignedTopics)
 	}
 
+	trash := api.Group("/trash", httpmw.RequireRole("admin"))
+	{
+		trash.GET("", h.getTrash)
+		trash.POST("/:type/:id/restore", h.restoreFromTrash)
+	}
+
+	admin := api.Group("/admin", httpmw.RequireRole("admin"))
+	{
+		admin.POST("/reconcile", h.reconcileStorage)
+		admin.GET("/reconcile", h.getReconcileStatus)
+	}
+
 	search := api.Group("/search")
 	{
 		search.POST("/students", httpmw.RequireRole("teacher", "admin"), h.searchStudents)
@@ -26,6 +26,7 @@ func (h *Handler) createTopic(c *gin.Context) {
 		userName.(string),
 		req.Title,
 		req.Description,
+		req.ChunkingStrategy,
 		req.Students,
 	)
 
@@ -37,11 +38,12 @@ func (h *Handler) createTopic(c *gin.Context) {
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"id":          topic.ID,
-		"title":       topic.Title,
-		"description": topic.Description,
-		"created_at":  topic.CreatedAt,
-		"message":     "Topic created successfully",
+		"id":                topic.ID,
+		"title":             topic.Title,
+		"description":       topic.Description,
+		"chunking_strategy": topic.ChunkingStrategy,
+		"created_at":        topic.CreatedAt,
+		"message":           "Topic created successfully",
 	})
 }
 
@@ -322,3 +324,101 @@ func (h *Handler) removeStudentFromTopic(c *gin.Context) {
 		"message": "Student removed from topic successfully",
 	})
 }
+
+func (h *Handler) deleteTopic(c *gin.Context) {
+	topicID := c.Param("id")
+	if topicID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "topic id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	err := h.services.Topic.DeleteTopic(
+		c.Request.Context(),
+		topicID,
+		userID.(string),
+		role.(string),
+	)
+
+	if err != nil {
+		if err.Error() == "access denied: only topic creator or admin can delete topic" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "topic not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "topic not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Topic deleted successfully",
+	})
+}
+
+func (h *Handler) updateTopicChunking(c *gin.Context) {
+	topicID := c.Param("id")
+	if topicID == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "topic id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+	role, _ := c.Get("role")
+
+	var req domain.UpdateTopicChunkingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+
+	topic, err := h.services.Topic.UpdateChunkingStrategy(
+		c.Request.Context(),
+		topicID,
+		userID.(string),
+		role.(string),
+		req.ChunkingStrategy,
+	)
+
+	if err != nil {
+		if err.Error() == "access denied: only topic creator or admin can change chunking strategy" {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+		if err.Error() == "topic not found" {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": "topic not found",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"id":                topic.ID,
+		"chunking_strategy": topic.ChunkingStrategy,
+		"message":           "Chunking strategy updated successfully",
+	})
+}
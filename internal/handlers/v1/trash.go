@@ -0,0 +1,84 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) getTrash(c *gin.Context) {
+	itemType := c.DefaultQuery("type", domain.TrashDataset)
+	page, _ := strconv.Atoi(c.DefaultQuery("page", "1"))
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "20"))
+
+	response, err := h.services.Trash.List(
+		c.Request.Context(),
+		itemType,
+		page,
+		limit,
+	)
+
+	if err != nil {
+		if err.Error() == "unknown trash type" {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "type must be dataset, topic or chat",
+			})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *Handler) restoreFromTrash(c *gin.Context) {
+	itemType := c.Param("type")
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "id is required",
+		})
+		return
+	}
+
+	userID, _ := c.Get("user_id")
+
+	err := h.services.Trash.Restore(
+		c.Request.Context(),
+		itemType,
+		id,
+		userID.(string),
+	)
+
+	if err != nil {
+		switch err.Error() {
+		case "unknown trash type":
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "type must be dataset, topic or chat",
+			})
+		case "item not found in trash":
+			c.JSON(http.StatusNotFound, gin.H{
+				"error": err.Error(),
+			})
+		case "dataset already exists for this topic":
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"success": true,
+		"message": "Restored successfully",
+	})
+}
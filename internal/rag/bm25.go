@@ -0,0 +1,141 @@
+package rag
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+var russianStopWords = map[string]struct{}{}
+
+func init() {
+	for _, w := range strings.Fields(`и в во не что он на я с со как а то все она так его но да ты к у же вы за бы по
+		только ее мне было вот от меня еще нет о из ему теперь когда даже ну ли если уже или ни быть был него до вас
+		нибудь опять уж вам ведь там потом себя ничего ей может они тут где есть надо ней для мы тебя их чем была сам
+		чтоб без будто чего раз тоже себе под будет ж тогда кто этот того потому этого какой совсем ним здесь этом один
+		почти мой тем чтобы нее сейчас были куда зачем всех никогда можно при наконец два об другой хоть после над
+		больше тот через эти нас про всего них какая много разве три эту моя впрочем хорошо свою этой перед иногда
+		лучше чуть том нельзя такой им более всегда конечно всю между это`) {
+		russianStopWords[w] = struct{}{}
+	}
+}
+
+// Tokenize разбивает текст на термы для лексического поиска: нижний регистр,
+// русские слова приводятся к основе, латиница и числа (имена переменных, формулы) остаются как есть
+func Tokenize(text string) []string {
+	words := strings.FieldsFunc(strings.ToLower(text), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+
+	terms := make([]string, 0, len(words))
+	for _, word := range words {
+		if _, stop := russianStopWords[word]; stop {
+			continue
+		}
+		if isCyrillic(word) {
+			word = StemRussian(word)
+		}
+		if word == "" {
+			continue
+		}
+		terms = append(terms, word)
+	}
+	return terms
+}
+
+func isCyrillic(word string) bool {
+	for _, r := range word {
+		if unicode.Is(unicode.Cyrillic, r) {
+			return true
+		}
+	}
+	return false
+}
+
+type ScoredIndex struct {
+	Index int
+	Score float64
+}
+
+// BM25Index — лексический индекс Okapi BM25 по набору текстов
+type BM25Index struct {
+	termFreqs []map[string]int
+	lengths   []int
+	avgLength float64
+	docFreq   map[string]int
+}
+
+func NewBM25Index(texts []string) *BM25Index {
+	idx := &BM25Index{
+		termFreqs: make([]map[string]int, len(texts)),
+		lengths:   make([]int, len(texts)),
+		docFreq:   make(map[string]int),
+	}
+
+	total := 0
+	for i, text := range texts {
+		terms := Tokenize(text)
+		tf := make(map[string]int, len(terms))
+		for _, term := range terms {
+			tf[term]++
+		}
+		for term := range tf {
+			idx.docFreq[term]++
+		}
+		idx.termFreqs[i] = tf
+		idx.lengths[i] = len(terms)
+		total += len(terms)
+	}
+
+	if len(texts) > 0 {
+		idx.avgLength = float64(total) / float64(len(texts))
+	}
+
+	return idx
+}
+
+// Search возвращает до k текстов с ненулевой релевантностью, по убыванию оценки
+func (idx *BM25Index) Search(query string, k int) []ScoredIndex {
+	n := float64(len(idx.termFreqs))
+	if n == 0 || idx.avgLength == 0 {
+		return []ScoredIndex{}
+	}
+
+	queryTerms := make(map[string]struct{})
+	for _, term := range Tokenize(query) {
+		queryTerms[term] = struct{}{}
+	}
+
+	results := make([]ScoredIndex, 0)
+	for i, tf := range idx.termFreqs {
+		score := 0.0
+		for term := range queryTerms {
+			freq := float64(tf[term])
+			if freq == 0 {
+				continue
+			}
+			df := float64(idx.docFreq[term])
+			idf := math.Log(1 + (n-df+0.5)/(df+0.5))
+			norm := bm25K1 * (1 - bm25B + bm25B*float64(idx.lengths[i])/idx.avgLength)
+			score += idf * freq * (bm25K1 + 1) / (freq + norm)
+		}
+		if score > 0 {
+			results = append(results, ScoredIndex{Index: i, Score: score})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	if k > 0 && len(results) > k {
+		results = results[:k]
+	}
+	return results
+}
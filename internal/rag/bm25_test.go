@@ -0,0 +1,81 @@
+package rag
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenize(t *testing.T) {
+	got := Tokenize("Это книги и Книгами, user_id = 42!")
+	want := []string{"книг", "книг", "user_id", "42"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tokenize = %q, want %q", got, want)
+	}
+}
+
+func searchIndexes(results []ScoredIndex) []int {
+	indexes := make([]int, len(results))
+	for i, r := range results {
+		indexes[i] = r.Index
+	}
+	return indexes
+}
+
+func TestBM25Search(t *testing.T) {
+	corpus := []string{
+		"Кошка сидит на окне",
+		"Собака бежит по улице, собака лает",
+		"Собака",
+		"Книги о программировании на Go",
+		"Длинный текст про собаку, в котором много других слов о погоде, городе и людях",
+	}
+	idx := NewBM25Index(corpus)
+
+	tests := []struct {
+		name  string
+		query string
+		k     int
+		want  []int
+	}{
+		// короткий документ выше при равной частоте, длинный — ниже всех с термом
+		{name: "length normalization", query: "собака", k: 0, want: []int{2, 1, 4}},
+		{name: "word forms match", query: "собаками", k: 0, want: []int{2, 1, 4}},
+		{name: "top k", query: "собака", k: 2, want: []int{2, 1}},
+		{name: "latin terms", query: "go", k: 0, want: []int{3}},
+		{name: "stop words only", query: "и на в", k: 0, want: []int{}},
+		{name: "unknown term", query: "самолёт", k: 0, want: []int{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIndexes(idx.Search(tt.query, tt.k))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Search(%q) = %v, want %v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBM25RareTermsWeighMore(t *testing.T) {
+	idx := NewBM25Index([]string{
+		"кошка дом",
+		"кошка сад",
+		"кошка двор",
+		"собака двор",
+	})
+
+	// «собака» встречается в одном документе, «кошка» в трёх: редкий терм решает
+	got := searchIndexes(idx.Search("кошка собака", 0))
+	if len(got) != 4 || got[0] != 3 {
+		t.Errorf("Search = %v, want document 3 first", got)
+	}
+}
+
+func TestBM25Empty(t *testing.T) {
+	if got := NewBM25Index(nil).Search("что угодно", 5); len(got) != 0 {
+		t.Errorf("Search on empty index = %v, want none", got)
+	}
+	if got := NewBM25Index([]string{"", "и на"}).Search("текст", 5); len(got) != 0 {
+		t.Errorf("Search on index without terms = %v, want none", got)
+	}
+}
@@ -0,0 +1,368 @@
+package rag
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	ChunkingHeader            = "header"
+	ChunkingFixedWindow       = "fixed_window"
+	ChunkingSemanticParagraph = "semantic_paragraph"
+	ChunkingCodeAware         = "code_aware"
+)
+
+// Chunker режет нормализованный markdown студента на документы для индексации
+type Chunker interface {
+	Chunk(textMd, studentID, assignmentID string, version int, sourceName string) []Document
+}
+
+func NewChunker(strategy string, opts ChunkOptions) (Chunker, error) {
+	opts = opts.normalize()
+
+	switch strategy {
+	case "", ChunkingHeader:
+		return &headerChunker{opts: opts}, nil
+	case ChunkingFixedWindow:
+		return &fixedWindowChunker{opts: opts}, nil
+	case ChunkingSemanticParagraph:
+		return &semanticParagraphChunker{opts: opts}, nil
+	case ChunkingCodeAware:
+		return &codeAwareChunker{opts: opts}, nil
+	default:
+		return nil, fmt.Errorf("unknown chunking strategy: %s", strategy)
+	}
+}
+
+func IsValidChunkingStrategy(strategy string) bool {
+	switch strategy {
+	case ChunkingHeader, ChunkingFixedWindow, ChunkingSemanticParagraph, ChunkingCodeAware:
+		return true
+	}
+	return false
+}
+
+// chunkSource — общие для всех чанков документа поля метаданных
+type chunkSource struct {
+	text          string
+	documentTitle string
+	sourceName    string
+	studentID     string
+	assignmentID  string
+	version       int
+}
+
+func newChunkSource(textMd, studentID, assignmentID string, version int, sourceName string) chunkSource {
+	if sourceName == "" {
+		sourceName = "document"
+	}
+	return chunkSource{
+		text:          textMd,
+		documentTitle: ExtractH1Title(textMd),
+		sourceName:    sourceName,
+		studentID:     studentID,
+		assignmentID:  assignmentID,
+		version:       version,
+	}
+}
+
+// document собирает чанк; start и end — байтовые границы фрагмента в исходном тексте
+func (s chunkSource) document(chunkID int, sectionTitle, content string, start, end int) Document {
+	startOffset := utf8.RuneCountInString(s.text[:start])
+	return Document{
+		PageContent: content,
+		Metadata: ChunkMetadata{
+			ChunkID:       chunkID,
+			SectionTitle:  sectionTitle,
+			DocumentTitle: s.documentTitle,
+			SourceName:    s.sourceName,
+			StudentID:     s.studentID,
+			AssignmentID:  s.assignmentID,
+			Version:       s.version,
+			StartOffset:   startOffset,
+			EndOffset:     startOffset + utf8.RuneCountInString(s.text[start:end]),
+		},
+	}
+}
+
+// sectionHeading возвращает заголовок раздела для префикса чанков.
+// У текста без H2 своего заголовка нет, его роль играет H1 документа
+func (s chunkSource) sectionHeading(heading string) string {
+	if heading == "" && s.documentTitle != "" {
+		return "# " + s.documentTitle
+	}
+	return heading
+}
+
+// headerChunker — разбиение по H2 с дроблением крупных разделов
+type headerChunker struct {
+	opts ChunkOptions
+}
+
+func (c *headerChunker) Chunk(textMd, studentID, assignmentID string, version int, sourceName string) []Document {
+	return ChunkStudentMarkdown(textMd, studentID, assignmentID, version, sourceName, c.opts)
+}
+
+// fixedWindowChunker игнорирует структуру документа и режет его окнами по словам.
+// Подходит для эссе и сплошного текста без разметки
+type fixedWindowChunker struct {
+	opts ChunkOptions
+}
+
+func (c *fixedWindowChunker) Chunk(textMd, studentID, assignmentID string, version int, sourceName string) []Document {
+	src := newChunkSource(textMd, studentID, assignmentID, version, sourceName)
+
+	full := trimSpan(textMd, span{start: 0, end: len(textMd)})
+	if full.start >= full.end {
+		return []Document{}
+	}
+
+	words := make([]span, 0)
+	for _, word := range splitters[len(splitters)-1].split(textMd, full) {
+		words = append(words, splitRecursive(textMd, word, c.opts.MaxTokens, len(splitters))...)
+	}
+
+	headers := h2HeaderPattern.FindAllStringSubmatchIndex(textMd, -1)
+
+	documents := make([]Document, 0)
+	for _, sp := range mergePieces(textMd, words, c.opts.MaxTokens, c.opts.OverlapTokens) {
+		documents = append(documents, src.document(len(documents), sectionTitleAt(textMd, headers, sp.start), textMd[sp.start:sp.end], sp.start, sp.end))
+	}
+
+	return documents
+}
+
+var h2HeaderPattern = regexp.MustCompile(`(?m)^##\s+(.+?)$`)
+
+// sectionTitleAt находит H2-раздел, в котором начинается позиция pos
+func sectionTitleAt(text string, headers [][]int, pos int) string {
+	if len(headers) == 0 {
+		return "Без заголовка"
+	}
+
+	title := "Введение"
+	for _, h := range headers {
+		if h[0] > pos {
+			break
+		}
+		title = strings.TrimSpace(text[h[2]:h[3]])
+	}
+	return title
+}
+
+// semanticParagraphChunker собирает чанки только из целых абзацев и не переходит
+// через заголовки любого уровня, поэтому каждый чанк остаётся в пределах одной темы
+type semanticParagraphChunker struct {
+	opts ChunkOptions
+}
+
+var anyHeaderPattern = regexp.MustCompile(`(?m)^#{2,6}\s+`)
+
+func (c *semanticParagraphChunker) Chunk(textMd, studentID, assignmentID string, version int, sourceName string) []Document {
+	src := newChunkSource(textMd, studentID, assignmentID, version, sourceName)
+	headerSplitter := boundarySplitter{pattern: anyHeaderPattern, before: true}
+	paragraphSplitter := splitters[1]
+
+	documents := make([]Document, 0)
+	for _, sec := range splitByH2Headers(textMd, false) {
+		heading, body, bodyStart := splitSectionHeading(sec.content)
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		offset := sec.start + bodyStart
+
+		prefix := ""
+		if heading = src.sectionHeading(heading); heading != "" {
+			prefix = heading + "\n\n"
+		}
+		budget := max(c.opts.MaxTokens-EstimateTokens(prefix), 1)
+
+		full := trimSpan(body, span{start: 0, end: len(body)})
+		for _, block := range headerSplitter.split(body, full) {
+			paragraphs := make([]span, 0)
+			for _, p := range paragraphSplitter.split(body, block) {
+				// абзац больше бюджета дробится по строкам и предложениям
+				paragraphs = append(paragraphs, splitRecursive(body, p, budget, 2)...)
+			}
+
+			for _, sp := range mergePieces(body, paragraphs, budget, 0) {
+				documents = append(documents, src.document(len(documents), sec.title, prefix+body[sp.start:sp.end], offset+sp.start, offset+sp.end))
+			}
+		}
+	}
+
+	return documents
+}
+
+// codeAwareChunker не разрывает fenced-блоки кода. Блок, не влезающий в чанк,
+// режется по строкам, и каждая часть снова оборачивается в ограждение с тем же языком
+type codeAwareChunker struct {
+	opts ChunkOptions
+}
+
+var codeFencePattern = regexp.MustCompile("(?ms)^```([^\\n`]*)\\n(.*?)^```[ \\t]*$")
+
+func (c *codeAwareChunker) Chunk(textMd, studentID, assignmentID string, version int, sourceName string) []Document {
+	src := newChunkSource(textMd, studentID, assignmentID, version, sourceName)
+
+	documents := make([]Document, 0)
+	for _, sec := range splitByH2Headers(textMd, false) {
+		heading, body, bodyStart := splitSectionHeading(sec.content)
+		if strings.TrimSpace(body) == "" {
+			continue
+		}
+		offset := sec.start + bodyStart
+
+		prefix := ""
+		if heading = src.sectionHeading(heading); heading != "" {
+			prefix = heading + "\n\n"
+		}
+		budget := max(c.opts.MaxTokens-EstimateTokens(prefix), 1)
+
+		for _, unit := range mergeUnits(codeAwareUnits(body, budget), budget) {
+			documents = append(documents, src.document(len(documents), sec.title, prefix+unit.text, offset+unit.start, offset+unit.end))
+		}
+	}
+
+	return documents
+}
+
+// textUnit — неделимая единица раздела и байтовые границы её фрагмента в теле раздела.
+// У частей разрезанного блока кода границы покрывают только строки кода без ограждений
+type textUnit struct {
+	text  string
+	start int
+	end   int
+	// codePart отмечает часть разрезанного блока кода: соседние части не склеиваются,
+	// иначе границы чанка накрыли бы код, разделённый в тексте чанка ограждениями
+	codePart bool
+}
+
+// codeAwareUnits делит раздел на неделимые единицы: блоки кода и куски прозы
+func codeAwareUnits(body string, budget int) []textUnit {
+	units := make([]textUnit, 0)
+
+	addProse := func(from, to int) {
+		text := body[from:to]
+		for _, sp := range splitText(text, budget, 0) {
+			units = append(units, textUnit{text: text[sp.start:sp.end], start: from + sp.start, end: from + sp.end})
+		}
+	}
+
+	cur := 0
+	for _, m := range codeFencePattern.FindAllStringSubmatchIndex(body, -1) {
+		addProse(cur, m[0])
+		cur = m[1]
+
+		block := body[m[0]:m[1]]
+		if EstimateTokens(block) <= budget {
+			units = append(units, textUnit{text: block, start: m[0], end: m[1]})
+			continue
+		}
+
+		lang := strings.TrimSpace(body[m[2]:m[3]])
+		for _, part := range splitCodeBlock(lang, body[m[4]:m[5]], budget) {
+			part.start += m[4]
+			part.end += m[4]
+			units = append(units, part)
+		}
+	}
+	addProse(cur, len(body))
+
+	return units
+}
+
+func splitCodeBlock(lang, code string, budget int) []textUnit {
+	open := "```" + lang + "\n"
+	const closeFence = "```"
+	lineBudget := max(budget-EstimateTokens(open+closeFence), 1)
+
+	parts := make([]textUnit, 0)
+	var buf strings.Builder
+	start, pos := 0, 0
+	flush := func() {
+		if buf.Len() > 0 {
+			text := buf.String()
+			// часть длинной строки обрывается посреди строки, а ограждение должно стоять на своей
+			if !strings.HasSuffix(text, "\n") {
+				text += "\n"
+			}
+			parts = append(parts, textUnit{text: open + text + closeFence, start: start, end: pos, codePart: true})
+			buf.Reset()
+		}
+	}
+
+	for _, line := range strings.SplitAfter(code, "\n") {
+		if line == "" {
+			continue
+		}
+		if buf.Len() > 0 && EstimateTokens(buf.String()+line) > lineBudget {
+			flush()
+		}
+		if EstimateTokens(line) > lineBudget {
+			// хвост длинной строки остаётся в буфере и продолжается следующими строками
+			segments := splitLongLine(line, lineBudget)
+			for _, segment := range segments[:len(segments)-1] {
+				start = pos
+				buf.WriteString(segment)
+				pos += len(segment)
+				flush()
+			}
+			line = segments[len(segments)-1]
+		}
+		if buf.Len() == 0 {
+			start = pos
+		}
+		buf.WriteString(line)
+		pos += len(line)
+	}
+	flush()
+
+	return parts
+}
+
+// splitLongLine режет строку длиннее budget на идущие подряд куски: по словам,
+// а слово без пробелов — по символам. Склейка кусков возвращает исходную строку
+func splitLongLine(line string, budget int) []string {
+	spans := splitText(line, budget, 0)
+
+	segments := make([]string, 0, len(spans))
+	prev := 0
+	for i, sp := range spans {
+		end := sp.end
+		if i == len(spans)-1 {
+			end = len(line)
+		}
+		segments = append(segments, line[prev:end])
+		prev = end
+	}
+	return segments
+}
+
+// mergeUnits жадно склеивает соседние единицы через пустую строку, пока чанк помещается в budget
+func mergeUnits(units []textUnit, budget int) []textUnit {
+	chunks := make([]textUnit, 0)
+
+	var cur textUnit
+	for _, unit := range units {
+		if cur.text == "" {
+			cur = unit
+			continue
+		}
+		if !(cur.codePart && unit.codePart) && EstimateTokens(cur.text+"\n\n"+unit.text) <= budget {
+			cur.text += "\n\n" + unit.text
+			cur.end = unit.end
+			cur.codePart = unit.codePart
+			continue
+		}
+		chunks = append(chunks, cur)
+		cur = unit
+	}
+	if cur.text != "" {
+		chunks = append(chunks, cur)
+	}
+
+	return chunks
+}
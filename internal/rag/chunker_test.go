@@ -0,0 +1,182 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+var chunkingStrategies = []string{ChunkingHeader, ChunkingFixedWindow, ChunkingSemanticParagraph, ChunkingCodeAware}
+
+func TestNewChunkerUnknownStrategy(t *testing.T) {
+	if _, err := NewChunker("random", ChunkOptions{}); err == nil {
+		t.Error("expected an error for an unknown strategy")
+	}
+	if IsValidChunkingStrategy("random") || IsValidChunkingStrategy("") {
+		t.Error("unknown and empty strategies must not be valid")
+	}
+	for _, strategy := range chunkingStrategies {
+		if !IsValidChunkingStrategy(strategy) {
+			t.Errorf("strategy %q must be valid", strategy)
+		}
+	}
+}
+
+func testDocument() string {
+	var b strings.Builder
+	b.WriteString("# Лабораторная работа\n\nКраткое введение перед разделами.\n\n## Теория\n\n")
+	for i := 0; i < 12; i++ {
+		fmt.Fprintf(&b, "Абзац %d рассказывает о теории и содержит несколько предложений. Второе предложение абзаца %d.\n\n", i, i)
+	}
+	b.WriteString("### Формулы\n\nE = mc² и ещё немного текста.\n\n## Код\n\nПример программы:\n\n```go\n")
+	for i := 0; i < 30; i++ {
+		fmt.Fprintf(&b, "fmt.Println(\"строка %d\")\n", i)
+	}
+	b.WriteString("```\n\nПосле кода идёт вывод.\n")
+	return b.String()
+}
+
+func TestChunkersInvariants(t *testing.T) {
+	text := testDocument()
+	opts := ChunkOptions{MaxTokens: 64, OverlapTokens: 8}
+
+	for _, strategy := range chunkingStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			chunker, err := NewChunker(strategy, opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs := chunker.Chunk(text, "student", "assignment", 3, "lab.md")
+			if len(docs) < 2 {
+				t.Fatalf("got %d chunks, want the document split", len(docs))
+			}
+			for i, doc := range docs {
+				if doc.Metadata.DocumentTitle != "Лабораторная работа" || doc.Metadata.StudentID != "student" || doc.Metadata.Version != 3 {
+					t.Errorf("chunk %d has wrong metadata: %+v", i, doc.Metadata)
+				}
+			}
+			checkChunks(t, text, docs, opts.MaxTokens)
+		})
+	}
+}
+
+func TestChunkersEmptyDocument(t *testing.T) {
+	for _, strategy := range chunkingStrategies {
+		for _, text := range []string{"", " \n\t\n"} {
+			chunker, err := NewChunker(strategy, ChunkOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs := chunker.Chunk(text, "s", "a", 1, "")
+			if len(docs) != 0 {
+				t.Errorf("%s: Chunk(%q) returned %d chunks, want none", strategy, text, len(docs))
+			}
+		}
+	}
+}
+
+func TestChunkersWithoutHeaders(t *testing.T) {
+	text := "Сплошной текст без заголовков.\n\nВторой абзац текста."
+
+	for _, strategy := range chunkingStrategies {
+		t.Run(strategy, func(t *testing.T) {
+			chunker, err := NewChunker(strategy, ChunkOptions{MaxTokens: 100})
+			if err != nil {
+				t.Fatal(err)
+			}
+			docs := chunker.Chunk(text, "s", "a", 1, "")
+			if len(docs) == 0 {
+				t.Fatal("header-less document produced no chunks")
+			}
+			for _, doc := range docs {
+				if doc.Metadata.SectionTitle != "Без заголовка" {
+					t.Errorf("section %q, want «Без заголовка»", doc.Metadata.SectionTitle)
+				}
+			}
+			checkChunks(t, text, docs, 100)
+		})
+	}
+}
+
+func TestFixedWindowSectionTitles(t *testing.T) {
+	text := "Введение.\n\n## Первый\n\nраз два три четыре пять шесть\n\n## Второй\n\nсемь восемь девять десять"
+	chunker, err := NewChunker(ChunkingFixedWindow, ChunkOptions{MaxTokens: 6, OverlapTokens: 0})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := chunker.Chunk(text, "s", "a", 1, "")
+
+	if docs[0].Metadata.SectionTitle != "Введение" {
+		t.Errorf("first chunk section %q, want «Введение»", docs[0].Metadata.SectionTitle)
+	}
+	if last := docs[len(docs)-1]; last.Metadata.SectionTitle != "Второй" {
+		t.Errorf("last chunk section %q, want «Второй»", last.Metadata.SectionTitle)
+	}
+	checkChunks(t, text, docs, 6)
+}
+
+func TestSemanticParagraphKeepsParagraphsWhole(t *testing.T) {
+	text := "## Раздел\n\nПервый абзац из пяти слов.\n\nВторой абзац тоже короткий.\n\n### Подтема\n\nТретий абзац под подзаголовком."
+	chunker, err := NewChunker(ChunkingSemanticParagraph, ChunkOptions{MaxTokens: 30})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := chunker.Chunk(text, "s", "a", 1, "")
+
+	for _, doc := range docs {
+		if strings.Contains(doc.PageContent, "Второй") && strings.Contains(doc.PageContent, "Третий") {
+			t.Errorf("chunk crosses a subheading: %q", doc.PageContent)
+		}
+	}
+	checkChunks(t, text, docs, 30)
+}
+
+func TestCodeAwareKeepsFencesBalanced(t *testing.T) {
+	text := testDocument()
+	chunker, err := NewChunker(ChunkingCodeAware, ChunkOptions{MaxTokens: 64})
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := chunker.Chunk(text, "s", "a", 1, "")
+
+	for i, doc := range docs {
+		if fences := strings.Count(doc.PageContent, "```"); fences%2 != 0 {
+			t.Errorf("chunk %d has unbalanced code fences: %q", i, doc.PageContent)
+		}
+		if strings.Contains(doc.PageContent, "fmt.Println") && !strings.Contains(doc.PageContent, "```go\n") {
+			t.Errorf("code chunk %d lost its fence language", i)
+		}
+	}
+	checkChunks(t, text, docs, 64)
+}
+
+func TestCodeAwareOverlongLine(t *testing.T) {
+	longLine := "x := []int{" + strings.TrimSuffix(strings.Repeat("1, ", 200), ", ") + "}"
+	noSpaces := "s := \"" + strings.Repeat("a", 400) + "\""
+	text := "## Код\n\n```go\nshort()\n" + longLine + "\n" + noSpaces + "\nend()\n```\n"
+
+	opts := ChunkOptions{MaxTokens: 40}
+	chunker, err := NewChunker(ChunkingCodeAware, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	docs := chunker.Chunk(text, "s", "a", 1, "")
+	if len(docs) < 3 {
+		t.Fatalf("got %d chunks, want overlong lines split", len(docs))
+	}
+
+	// смещения частей идут подряд и вместе покрывают весь код
+	runes := []rune(text)
+	var restored strings.Builder
+	for i, doc := range docs {
+		content := strings.TrimPrefix(doc.PageContent, "## Код\n\n")
+		if !strings.HasPrefix(content, "```go\n") || !strings.HasSuffix(content, "\n```") {
+			t.Errorf("chunk %d is not a fenced code block: %q", i, content)
+		}
+		restored.WriteString(string(runes[doc.Metadata.StartOffset:doc.Metadata.EndOffset]))
+	}
+	if want := "short()\n" + longLine + "\n" + noSpaces + "\nend()\n"; restored.String() != want {
+		t.Errorf("code is not restored from chunks:\n%q\nwant\n%q", restored.String(), want)
+	}
+	checkChunks(t, text, docs, opts.MaxTokens)
+}
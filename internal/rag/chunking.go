@@ -3,6 +3,7 @@ package rag
 import (
 	"regexp"
 	"strings"
+	"unicode"
 )
 
 type ChunkMetadata struct {
@@ -13,6 +14,10 @@ type ChunkMetadata struct {
 	StudentID     string `json:"student_id"`
 	AssignmentID  string `json:"assignment_id"`
 	Version       int    `json:"version"`
+	// StartOffset и EndOffset — границы процитированного фрагмента в нормализованном markdown, в символах.
+	// Синтетический префикс с заголовком раздела в них не входит
+	StartOffset int `json:"start_offset"`
+	EndOffset   int `json:"end_offset"`
 }
 
 type Document struct {
@@ -23,54 +28,90 @@ type Document struct {
 type section struct {
 	title   string
 	content string
+	// start — байтовое смещение content в исходном тексте
+	start int
 }
 
+// ChunkStudentMarkdown режет документ по H2-разделам. Разделы больше opts.MaxTokens
+// дробятся по H3, абзацам и предложениям, каждый подчанк начинается с заголовка раздела.
+// Текст до первого H2 и документы без заголовков тоже попадают в индекс
 func ChunkStudentMarkdown(
 	textMd string,
 	studentID string,
 	assignmentID string,
 	version int,
 	sourceName string,
+	opts ChunkOptions,
 ) []Document {
 	if textMd == "" {
 		return []Document{}
 	}
 
-	if sourceName == "" {
-		sourceName = "document"
-	}
-
-	documentTitle := ExtractH1Title(textMd)
+	opts = opts.normalize()
+	src := newChunkSource(textMd, studentID, assignmentID, version, sourceName)
 
-	const ignoreBeforeFirstHeader = true
+	const ignoreBeforeFirstHeader = false
 	sections := splitByH2Headers(textMd, ignoreBeforeFirstHeader)
 
 	documents := make([]Document, 0, len(sections))
 
-	for idx, sec := range sections {
-		if strings.TrimSpace(sec.content) == "" {
+	for _, sec := range sections {
+		heading, body, bodyStart := splitSectionHeading(sec.content)
+		if strings.TrimSpace(body) == "" {
 			continue
 		}
 
-		metadata := ChunkMetadata{
-			ChunkID:       idx,
-			SectionTitle:  sec.title,
-			DocumentTitle: documentTitle,
-			SourceName:    sourceName,
-			StudentID:     studentID,
-			AssignmentID:  assignmentID,
-			Version:       version,
+		offset := sec.start + bodyStart
+		for _, part := range chunkSection(src.sectionHeading(heading), body, opts) {
+			documents = append(documents, src.document(len(documents), sec.title, part.content, offset+part.start, offset+part.end))
 		}
+	}
 
-		doc := Document{
-			PageContent: sec.content,
-			Metadata:    metadata,
-		}
+	return documents
+}
+
+var leadingHeadingPattern = regexp.MustCompile(`^#{1,2}\s+.*(?:\n|$)`)
 
-		documents = append(documents, doc)
+// splitSectionHeading отделяет строку заголовка H1/H2 от тела раздела
+// и возвращает байтовое смещение тела внутри content
+func splitSectionHeading(content string) (string, string, int) {
+	loc := leadingHeadingPattern.FindStringIndex(content)
+	if loc == nil {
+		return "", content, 0
 	}
+	rest := content[loc[1]:]
+	return strings.TrimSpace(content[:loc[1]]), strings.TrimSpace(rest), loc[1] + leadingSpaceLen(rest)
+}
 
-	return documents
+func leadingSpaceLen(text string) int {
+	return len(text) - len(strings.TrimLeftFunc(text, unicode.IsSpace))
+}
+
+// chunkPart — текст чанка и байтовые границы его фрагмента в теле раздела
+type chunkPart struct {
+	content string
+	start   int
+	end     int
+}
+
+func chunkSection(heading, body string, opts ChunkOptions) []chunkPart {
+	prefix := ""
+	if heading != "" {
+		prefix = heading + "\n\n"
+	}
+
+	if EstimateTokens(prefix+body) <= opts.MaxTokens {
+		return []chunkPart{{content: prefix + body, start: 0, end: len(body)}}
+	}
+
+	budget := max(opts.MaxTokens-EstimateTokens(prefix), 1)
+	spans := splitText(body, budget, opts.OverlapTokens)
+
+	chunks := make([]chunkPart, 0, len(spans))
+	for _, sp := range spans {
+		chunks = append(chunks, chunkPart{content: prefix + body[sp.start:sp.end], start: sp.start, end: sp.end})
+	}
+	return chunks
 }
 
 func ExtractH1Title(text string) string {
@@ -111,6 +152,7 @@ func splitByH2Headers(text string, ignoreBeforeFirst bool) []section {
 			sections = append(sections, section{
 				title:   "Без заголовка",
 				content: strings.TrimSpace(text),
+				start:   leadingSpaceLen(text),
 			})
 		}
 		return sections
@@ -122,6 +164,7 @@ func splitByH2Headers(text string, ignoreBeforeFirst bool) []section {
 			sections = append(sections, section{
 				title:   "Введение",
 				content: preText,
+				start:   leadingSpaceLen(text),
 			})
 		}
 	}
@@ -142,6 +185,7 @@ func splitByH2Headers(text string, ignoreBeforeFirst bool) []section {
 			sections = append(sections, section{
 				title:   h.title,
 				content: sectionContent,
+				start:   sectionStart,
 			})
 		}
 	}
@@ -0,0 +1,362 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffInsert
+	DiffDelete
+)
+
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+const (
+	SectionAdded    = "added"
+	SectionRemoved  = "removed"
+	SectionModified = "modified"
+)
+
+type SectionChange struct {
+	Title        string
+	Status       string
+	LinesAdded   int
+	LinesRemoved int
+}
+
+// maxDiffEdits ограничивает глубину поиска средней змейки. Время Майерса растёт как
+// O((N+M)·D), поэтому при большем расхождении изменённый участок отдаётся
+// как замена целиком: diff остаётся корректным, но не минимальным
+const maxDiffEdits = 2000
+
+// DiffLines строит построчный diff алгоритмом Майерса в линейной памяти
+// (поиск средней змейки и разбиение пополам)
+func DiffLines(oldLines, newLines []string) []DiffLine {
+	// строки заменяются номерами, чтобы сравнение в змейках не сравнивало строки
+	ids := make(map[string]int, len(oldLines)+len(newLines))
+	lineIDs := func(lines []string) []int {
+		out := make([]int, len(lines))
+		for i, line := range lines {
+			id, ok := ids[line]
+			if !ok {
+				id = len(ids)
+				ids[line] = id
+			}
+			out[i] = id
+		}
+		return out
+	}
+
+	d := &myersDiff{
+		oldLines: oldLines,
+		newLines: newLines,
+		a:        lineIDs(oldLines),
+		b:        lineIDs(newLines),
+		result:   make([]DiffLine, 0, len(oldLines)+len(newLines)),
+	}
+	d.compare(0, len(oldLines), 0, len(newLines))
+
+	return d.result
+}
+
+type myersDiff struct {
+	oldLines, newLines []string
+	a, b               []int
+	result             []DiffLine
+}
+
+// compare дописывает в result diff участков a[a0:a1] и b[b0:b1]
+func (d *myersDiff) compare(a0, a1, b0, b1 int) {
+	for a0 < a1 && b0 < b1 && d.a[a0] == d.b[b0] {
+		d.result = append(d.result, DiffLine{Op: DiffEqual, Text: d.oldLines[a0]})
+		a0++
+		b0++
+	}
+
+	suffix := 0
+	for a0 < a1-suffix && b0 < b1-suffix && d.a[a1-1-suffix] == d.b[b1-1-suffix] {
+		suffix++
+	}
+	a1 -= suffix
+	b1 -= suffix
+
+	switch {
+	case a0 == a1:
+		d.insert(b0, b1)
+	case b0 == b1:
+		d.delete(a0, a1)
+	default:
+		if x, y, ok := d.bisect(a0, a1, b0, b1); ok {
+			d.compare(a0, a0+x, b0, b0+y)
+			d.compare(a0+x, a1, b0+y, b1)
+		} else {
+			d.delete(a0, a1)
+			d.insert(b0, b1)
+		}
+	}
+
+	for i := a1; i < a1+suffix; i++ {
+		d.result = append(d.result, DiffLine{Op: DiffEqual, Text: d.oldLines[i]})
+	}
+}
+
+func (d *myersDiff) insert(b0, b1 int) {
+	for _, line := range d.newLines[b0:b1] {
+		d.result = append(d.result, DiffLine{Op: DiffInsert, Text: line})
+	}
+}
+
+func (d *myersDiff) delete(a0, a1 int) {
+	for _, line := range d.oldLines[a0:a1] {
+		d.result = append(d.result, DiffLine{Op: DiffDelete, Text: line})
+	}
+}
+
+// bisect ищет среднюю змейку одновременно с начала и с конца участков и возвращает
+// точку разбиения относительно a0 и b0. false — общих строк нет или превышен maxDiffEdits
+func (d *myersDiff) bisect(a0, a1, b0, b1 int) (int, int, bool) {
+	a, b := d.a[a0:a1], d.b[b0:b1]
+	n, m := len(a), len(b)
+
+	maxD := (n + m + 1) / 2
+	offset := maxD
+	forward := make([]int, 2*maxD+2)
+	backward := make([]int, 2*maxD+2)
+	for i := range forward {
+		forward[i] = -1
+		backward[i] = -1
+	}
+	forward[offset+1] = 0
+	backward[offset+1] = 0
+
+	delta := n - m
+	// при нечётной разнице длин пути встречаются на прямом проходе, иначе на обратном
+	front := delta%2 != 0
+
+	// диагонали, вышедшие за границы участков, дальше не просматриваются
+	kStart, kEnd, rStart, rEnd := 0, 0, 0, 0
+
+	for step := 0; step < maxD; step++ {
+		if step > maxDiffEdits {
+			return 0, 0, false
+		}
+
+		for k := -step + kStart; k <= step-kEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && forward[i-1] < forward[i+1]) {
+				x = forward[i+1]
+			} else {
+				x = forward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+			forward[i] = x
+
+			switch {
+			case x > n:
+				kEnd += 2
+			case y > m:
+				kStart += 2
+			case front:
+				j := offset + delta - k
+				if j >= 0 && j < len(backward) && backward[j] != -1 && x >= n-backward[j] {
+					return x, y, true
+				}
+			}
+		}
+
+		for k := -step + rStart; k <= step-rEnd; k += 2 {
+			i := offset + k
+			var x int
+			if k == -step || (k != step && backward[i-1] < backward[i+1]) {
+				x = backward[i+1]
+			} else {
+				x = backward[i-1] + 1
+			}
+			y := x - k
+			for x < n && y < m && a[n-x-1] == b[m-y-1] {
+				x++
+				y++
+			}
+			backward[i] = x
+
+			switch {
+			case x > n:
+				rEnd += 2
+			case y > m:
+				rStart += 2
+			case !front:
+				j := offset + delta - k
+				if j >= 0 && j < len(forward) && forward[j] != -1 {
+					fx := forward[j]
+					fy := offset + fx - j
+					if fx >= n-x {
+						return fx, fy, true
+					}
+				}
+			}
+		}
+	}
+
+	return 0, 0, false
+}
+
+// UnifiedDiff возвращает diff в формате unified с contextLines строками контекста
+// и количество добавленных и удалённых строк
+func UnifiedDiff(oldName, newName, oldText, newText string, contextLines int) (string, int, int) {
+	lines := DiffLines(splitLines(oldText), splitLines(newText))
+
+	added, removed := 0, 0
+	changed := make([]int, 0)
+	for i, line := range lines {
+		switch line.Op {
+		case DiffInsert:
+			added++
+			changed = append(changed, i)
+		case DiffDelete:
+			removed++
+			changed = append(changed, i)
+		}
+	}
+
+	if len(changed) == 0 {
+		return "", 0, 0
+	}
+
+	// номера строк в старом и новом тексте перед каждой позицией diff
+	oldPos := make([]int, len(lines)+1)
+	newPos := make([]int, len(lines)+1)
+	for i, line := range lines {
+		oldPos[i+1] = oldPos[i]
+		newPos[i+1] = newPos[i]
+		if line.Op != DiffInsert {
+			oldPos[i+1]++
+		}
+		if line.Op != DiffDelete {
+			newPos[i+1]++
+		}
+	}
+
+	var buf strings.Builder
+	buf.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", oldName, newName))
+
+	for i := 0; i < len(changed); {
+		start := max(changed[i]-contextLines, 0)
+		j := i
+		for j+1 < len(changed) && changed[j+1]-changed[j] <= 2*contextLines+1 {
+			j++
+		}
+		end := min(changed[j]+contextLines+1, len(lines))
+
+		oldCount := oldPos[end] - oldPos[start]
+		newCount := newPos[end] - newPos[start]
+		buf.WriteString(fmt.Sprintf("@@ -%s +%s @@\n",
+			hunkRange(oldPos[start], oldCount), hunkRange(newPos[start], newCount)))
+
+		for _, line := range lines[start:end] {
+			switch line.Op {
+			case DiffEqual:
+				buf.WriteString(" ")
+			case DiffInsert:
+				buf.WriteString("+")
+			case DiffDelete:
+				buf.WriteString("-")
+			}
+			buf.WriteString(line.Text)
+			buf.WriteString("\n")
+		}
+
+		i = j + 1
+	}
+
+	return buf.String(), added, removed
+}
+
+func hunkRange(start, count int) string {
+	if count == 0 {
+		return fmt.Sprintf("%d,0", start)
+	}
+	if count == 1 {
+		return fmt.Sprintf("%d", start+1)
+	}
+	return fmt.Sprintf("%d,%d", start+1, count)
+}
+
+func splitLines(text string) []string {
+	if text == "" {
+		return []string{}
+	}
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.TrimSuffix(text, "\n"), "\n")
+}
+
+// DiffSections сравнивает H2-разделы двух версий документа.
+// Разделы с одинаковыми заголовками сопоставляются по порядку появления
+func DiffSections(oldText, newText string) []SectionChange {
+	oldSections := splitByH2Headers(oldText, false)
+	newSections := splitByH2Headers(newText, false)
+
+	oldByTitle := make(map[string][]section)
+	for _, sec := range oldSections {
+		oldByTitle[sec.title] = append(oldByTitle[sec.title], sec)
+	}
+
+	matched := make(map[string]int)
+	changes := make([]SectionChange, 0)
+
+	for _, sec := range newSections {
+		candidates := oldByTitle[sec.title]
+		if matched[sec.title] >= len(candidates) {
+			changes = append(changes, SectionChange{
+				Title:      sec.title,
+				Status:     SectionAdded,
+				LinesAdded: len(splitLines(sec.content)),
+			})
+			continue
+		}
+
+		old := candidates[matched[sec.title]]
+		matched[sec.title]++
+
+		if old.content == sec.content {
+			continue
+		}
+
+		change := SectionChange{Title: sec.title, Status: SectionModified}
+		for _, line := range DiffLines(splitLines(old.content), splitLines(sec.content)) {
+			switch line.Op {
+			case DiffInsert:
+				change.LinesAdded++
+			case DiffDelete:
+				change.LinesRemoved++
+			}
+		}
+		changes = append(changes, change)
+	}
+
+	seen := make(map[string]int)
+	for _, sec := range oldSections {
+		seen[sec.title]++
+		if seen[sec.title] <= matched[sec.title] {
+			continue
+		}
+		changes = append(changes, SectionChange{
+			Title:        sec.title,
+			Status:       SectionRemoved,
+			LinesRemoved: len(splitLines(sec.content)),
+		})
+	}
+
+	return changes
+}
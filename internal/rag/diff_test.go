@@ -0,0 +1,209 @@
+package rag
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+)
+
+// applyDiff восстанавливает старый и новый текст из diff
+func applyDiff(lines []DiffLine) ([]string, []string) {
+	oldLines, newLines := make([]string, 0), make([]string, 0)
+	for _, line := range lines {
+		if line.Op != DiffInsert {
+			oldLines = append(oldLines, line.Text)
+		}
+		if line.Op != DiffDelete {
+			newLines = append(newLines, line.Text)
+		}
+	}
+	return oldLines, newLines
+}
+
+func countOps(lines []DiffLine) (added, removed int) {
+	for _, line := range lines {
+		switch line.Op {
+		case DiffInsert:
+			added++
+		case DiffDelete:
+			removed++
+		}
+	}
+	return added, removed
+}
+
+func TestDiffLines(t *testing.T) {
+	tests := []struct {
+		name        string
+		oldLines    []string
+		newLines    []string
+		wantAdded   int
+		wantRemoved int
+	}{
+		{name: "both empty", oldLines: []string{}, newLines: []string{}},
+		{name: "old empty", oldLines: []string{}, newLines: []string{"a", "b"}, wantAdded: 2},
+		{name: "new empty", oldLines: []string{"a", "b"}, newLines: []string{}, wantRemoved: 2},
+		{name: "equal", oldLines: []string{"a", "b", "c"}, newLines: []string{"a", "b", "c"}},
+		{name: "insert in middle", oldLines: []string{"a", "c"}, newLines: []string{"a", "b", "c"}, wantAdded: 1},
+		{name: "delete in middle", oldLines: []string{"a", "b", "c"}, newLines: []string{"a", "c"}, wantRemoved: 1},
+		{name: "replace line", oldLines: []string{"a", "b", "c"}, newLines: []string{"a", "x", "c"}, wantAdded: 1, wantRemoved: 1},
+		{
+			name:        "classic myers example",
+			oldLines:    strings.Split("ABCABBA", ""),
+			newLines:    strings.Split("CBABAC", ""),
+			wantAdded:   2,
+			wantRemoved: 3,
+		},
+		{name: "nothing in common", oldLines: []string{"a", "b"}, newLines: []string{"c", "d", "e"}, wantAdded: 3, wantRemoved: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			lines := DiffLines(tt.oldLines, tt.newLines)
+
+			gotOld, gotNew := applyDiff(lines)
+			if strings.Join(gotOld, "\n") != strings.Join(tt.oldLines, "\n") {
+				t.Errorf("old text not restored: %q", gotOld)
+			}
+			if strings.Join(gotNew, "\n") != strings.Join(tt.newLines, "\n") {
+				t.Errorf("new text not restored: %q", gotNew)
+			}
+
+			added, removed := countOps(lines)
+			if added != tt.wantAdded || removed != tt.wantRemoved {
+				t.Errorf("got +%d -%d, want +%d -%d", added, removed, tt.wantAdded, tt.wantRemoved)
+			}
+		})
+	}
+}
+
+// lcsLength — эталонная длина наибольшей общей подпоследовательности для проверки минимальности
+func lcsLength(a, b []string) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			switch {
+			case a[i] == b[j]:
+				cur[j+1] = prev[j] + 1
+			case prev[j+1] > cur[j]:
+				cur[j+1] = prev[j+1]
+			default:
+				cur[j+1] = cur[j]
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
+
+func TestDiffLinesRandomIsMinimal(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, rng.Intn(40))
+		for i := range lines {
+			lines[i] = string(rune('a' + rng.Intn(4)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 500; i++ {
+		oldLines, newLines := randomLines(), randomLines()
+		lines := DiffLines(oldLines, newLines)
+
+		gotOld, gotNew := applyDiff(lines)
+		if strings.Join(gotOld, "") != strings.Join(oldLines, "") || strings.Join(gotNew, "") != strings.Join(newLines, "") {
+			t.Fatalf("texts not restored for %q -> %q", oldLines, newLines)
+		}
+
+		added, removed := countOps(lines)
+		lcs := lcsLength(oldLines, newLines)
+		if added != len(newLines)-lcs || removed != len(oldLines)-lcs {
+			t.Fatalf("diff of %q -> %q is not minimal: +%d -%d, lcs %d", oldLines, newLines, added, removed, lcs)
+		}
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	t.Run("equal", func(t *testing.T) {
+		out, added, removed := UnifiedDiff("a", "b", "x\ny\n", "x\ny\n", 3)
+		if out != "" || added != 0 || removed != 0 {
+			t.Errorf("got %q +%d -%d, want empty diff", out, added, removed)
+		}
+	})
+
+	t.Run("empty", func(t *testing.T) {
+		out, added, removed := UnifiedDiff("a", "b", "", "", 3)
+		if out != "" || added != 0 || removed != 0 {
+			t.Errorf("got %q +%d -%d, want empty diff", out, added, removed)
+		}
+	})
+
+	t.Run("from empty", func(t *testing.T) {
+		out, added, removed := UnifiedDiff("a", "b", "", "x\ny\n", 3)
+		want := "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n"
+		if out != want || added != 2 || removed != 0 {
+			t.Errorf("got %q +%d -%d, want %q", out, added, removed, want)
+		}
+	})
+
+	t.Run("hunk with context", func(t *testing.T) {
+		oldText := "1\n2\n3\n4\n5\n6\n7\n"
+		newText := "1\n2\n3\nfour\n5\n6\n7\n"
+		out, added, removed := UnifiedDiff("a", "b", oldText, newText, 1)
+		want := "--- a\n+++ b\n@@ -3,3 +3,3 @@\n 3\n-4\n+four\n 5\n"
+		if out != want || added != 1 || removed != 1 {
+			t.Errorf("got %q +%d -%d, want %q", out, added, removed, want)
+		}
+	})
+
+	t.Run("large divergent", func(t *testing.T) {
+		const lines = 8000
+		var oldText, newText strings.Builder
+		for i := 0; i < lines; i++ {
+			fmt.Fprintf(&oldText, "old line %d with some text\n", i)
+			fmt.Fprintf(&newText, "new line %d with other text\n", i)
+		}
+
+		started := time.Now()
+		out, added, removed := UnifiedDiff("a", "b", oldText.String(), newText.String(), 3)
+		if elapsed := time.Since(started); elapsed > 2*time.Second {
+			t.Errorf("diff took %s", elapsed)
+		}
+		if added != lines || removed != lines {
+			t.Errorf("got +%d -%d, want +%d -%d", added, removed, lines, lines)
+		}
+		if !strings.HasPrefix(out, fmt.Sprintf("--- a\n+++ b\n@@ -1,%d +1,%d @@\n", lines, lines)) {
+			t.Errorf("unexpected hunk header: %q", out[:min(len(out), 60)])
+		}
+	})
+
+	t.Run("large interleaved exceeds edit limit", func(t *testing.T) {
+		// общие строки через одну заставляют искать далеко за maxDiffEdits
+		var oldLines, newLines []string
+		for i := 0; i < 4*maxDiffEdits; i++ {
+			oldLines = append(oldLines, "same", fmt.Sprintf("old %d", i))
+			newLines = append(newLines, "same", fmt.Sprintf("new %d", i))
+		}
+
+		lines := DiffLines(oldLines, newLines)
+		gotOld, gotNew := applyDiff(lines)
+		if strings.Join(gotOld, "\n") != strings.Join(oldLines, "\n") || strings.Join(gotNew, "\n") != strings.Join(newLines, "\n") {
+			t.Fatal("texts not restored")
+		}
+	})
+}
+
+func BenchmarkUnifiedDiffDivergent(b *testing.B) {
+	var oldText, newText strings.Builder
+	for i := 0; i < 8000; i++ {
+		fmt.Fprintf(&oldText, "old line %d\n", i)
+		fmt.Fprintf(&newText, "new line %d\n", i)
+	}
+	b.ReportAllocs()
+	for b.Loop() {
+		UnifiedDiff("a", "b", oldText.String(), newText.String(), 3)
+	}
+}
@@ -0,0 +1,39 @@
+package rag
+
+import "sort"
+
+// RankedList — ранжированный по убыванию релевантности список идентификаторов с весом источника
+type RankedList struct {
+	IDs    []int
+	Weight float64
+}
+
+// ReciprocalRankFusion объединяет несколько ранжирований: score(id) = Σ weight / (k + rank).
+// Ранги начинаются с единицы, k сглаживает вклад верхних позиций
+func ReciprocalRankFusion(k int, lists ...RankedList) []ScoredIndex {
+	scores := make(map[int]float64)
+	order := make([]int, 0)
+
+	for _, list := range lists {
+		if list.Weight <= 0 {
+			continue
+		}
+		for rank, id := range list.IDs {
+			if _, seen := scores[id]; !seen {
+				order = append(order, id)
+			}
+			scores[id] += list.Weight / float64(k+rank+1)
+		}
+	}
+
+	fused := make([]ScoredIndex, 0, len(order))
+	for _, id := range order {
+		fused = append(fused, ScoredIndex{Index: id, Score: scores[id]})
+	}
+
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+
+	return fused
+}
@@ -0,0 +1,94 @@
+package rag
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func TestReciprocalRankFusion(t *testing.T) {
+	tests := []struct {
+		name  string
+		k     int
+		lists []RankedList
+		want  []int
+	}{
+		{
+			name:  "single list keeps order",
+			k:     60,
+			lists: []RankedList{{IDs: []int{3, 1, 2}, Weight: 1}},
+			want:  []int{3, 1, 2},
+		},
+		{
+			name: "found by both lists wins",
+			k:    60,
+			lists: []RankedList{
+				{IDs: []int{1, 2, 3}, Weight: 1},
+				{IDs: []int{4, 3, 5}, Weight: 1},
+			},
+			want: []int{3, 1, 4, 2, 5},
+		},
+		{
+			name: "weight favours a list",
+			k:    60,
+			lists: []RankedList{
+				{IDs: []int{1, 2}, Weight: 1},
+				{IDs: []int{2, 1}, Weight: 3},
+			},
+			want: []int{2, 1},
+		},
+		{
+			name: "zero weight disables a list",
+			k:    60,
+			lists: []RankedList{
+				{IDs: []int{1, 2}, Weight: 1},
+				{IDs: []int{9, 2, 1}, Weight: 0},
+			},
+			want: []int{1, 2},
+		},
+		{
+			name: "ties keep first appearance",
+			k:    60,
+			lists: []RankedList{
+				{IDs: []int{1}, Weight: 1},
+				{IDs: []int{2}, Weight: 1},
+			},
+			want: []int{1, 2},
+		},
+		{
+			name:  "no lists",
+			k:     60,
+			lists: nil,
+			want:  []int{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := searchIndexes(ReciprocalRankFusion(tt.k, tt.lists...))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReciprocalRankFusion = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReciprocalRankFusionScores(t *testing.T) {
+	fused := ReciprocalRankFusion(10,
+		RankedList{IDs: []int{7, 8}, Weight: 1},
+		RankedList{IDs: []int{8}, Weight: 2},
+	)
+
+	want := map[int]float64{
+		7: 1.0 / 11,
+		8: 1.0/12 + 2.0/11,
+	}
+	for _, item := range fused {
+		if math.Abs(item.Score-want[item.Index]) > 1e-12 {
+			t.Errorf("score of %d = %v, want %v", item.Index, item.Score, want[item.Index])
+		}
+	}
+	if len(fused) != 2 || fused[0].Index != 8 {
+		t.Errorf("fused = %v, want 8 first", fused)
+	}
+}
@@ -0,0 +1,35 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+)
+
+// minClaimWords — более короткие предложения («Да.», «См. ниже:») не проверяются
+const minClaimWords = 3
+
+var (
+	markerPattern      = regexp.MustCompile(`\s*\[\d+(?:\s*,\s*\d+)*\]`)
+	claimBoundary      = regexp.MustCompile(`[.!?…]+["»)]*\s+|\n+`)
+	listItemPrefix     = regexp.MustCompile(`^(?:[-*+•]|\d+[.)]|#{1,6})\s+`)
+	fencedBlockPattern = regexp.MustCompile("(?s)```.*?```")
+)
+
+// SplitClaims делит ответ модели на утверждения для проверки опоры на контекст.
+// Блоки кода, маркеры цитирования и разметка списков отбрасываются
+func SplitClaims(answer string) []string {
+	text := fencedBlockPattern.ReplaceAllString(answer, "\n")
+	text = markerPattern.ReplaceAllString(text, "")
+
+	claims := make([]string, 0)
+	for _, part := range claimBoundary.Split(text, -1) {
+		claim := strings.TrimSpace(listItemPrefix.ReplaceAllString(strings.TrimSpace(part), ""))
+		claim = strings.Trim(claim, "*_")
+		if len(strings.Fields(claim)) < minClaimWords {
+			continue
+		}
+		claims = append(claims, claim)
+	}
+
+	return claims
+}
@@ -0,0 +1,171 @@
+package rag
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// maxMarkerLen ограничивает длину буфера после «[»: более длинная скобка точно не маркер
+const maxMarkerLen = 24
+
+var markerListPattern = regexp.MustCompile(`^\s*\d+(?:\s*,\s*\d+)*\s*$`)
+
+// MarkerFilter проверяет маркеры цитирования [n] в потоке ответа модели.
+// Маркер считается корректным, если n ссылается на один из переданных фрагментов,
+// некорректные номера вырезаются вместе с пробелом перед маркером, чтобы не оставалось двойных
+// пробелов. Маркеры проверяются и сразу после слова (слово[7]), скобки внутри кода не трогаются.
+// Фильтр не потокобезопасен
+type MarkerFilter struct {
+	fragments int
+
+	pending strings.Builder
+	prev    rune
+
+	// space — пробелы, придержанные до следующего символа: если за ними идёт вырезанный маркер,
+	// они выбрасываются. dropSpace — маркер в начале строки вырезан, пробелы после него не нужны
+	space     strings.Builder
+	dropSpace bool
+
+	// ticks — длина текущей серии обратных кавычек, fence — длина открывшей код серии
+	ticks int
+	fence int
+
+	cited []int
+	seen  map[int]bool
+}
+
+func NewMarkerFilter(fragments int) *MarkerFilter {
+	return &MarkerFilter{
+		fragments: fragments,
+		seen:      make(map[int]bool),
+	}
+}
+
+// Write принимает очередной кусок ответа и возвращает текст, который уже можно отдать клиенту.
+// Начало возможного маркера и пробелы перед ним придерживаются до закрывающей скобки
+func (f *MarkerFilter) Write(delta string) string {
+	var out strings.Builder
+	for _, r := range delta {
+		f.writeRune(&out, r)
+	}
+	return out.String()
+}
+
+// Flush возвращает придержанный хвост, когда поток закончился
+func (f *MarkerFilter) Flush() string {
+	rest := f.space.String() + f.pending.String()
+	f.space.Reset()
+	f.pending.Reset()
+	return rest
+}
+
+// Cited возвращает номера фрагментов в порядке первого упоминания в ответе
+func (f *MarkerFilter) Cited() []int {
+	return f.cited
+}
+
+func (f *MarkerFilter) writeRune(out *strings.Builder, r rune) {
+	if f.pending.Len() > 0 {
+		switch {
+		case r == ']':
+			marker := f.resolve(strings.TrimPrefix(f.pending.String(), "["))
+			f.pending.Reset()
+			if marker == "" {
+				f.drop()
+				return
+			}
+			f.emit(out, f.takeSpace()+marker)
+			return
+		case (unicode.IsDigit(r) || r == ',' || r == ' ') && f.pending.Len() < maxMarkerLen:
+			f.pending.WriteRune(r)
+			return
+		default:
+			// не маркер: отдаём буфер как есть и обрабатываем символ заново
+			f.emit(out, f.Flush())
+		}
+	}
+
+	if r == '`' {
+		f.dropSpace = false
+		f.ticks++
+		f.emit(out, f.takeSpace()+string(r))
+		return
+	}
+	if f.ticks > 0 {
+		switch {
+		case f.fence == 0:
+			f.fence = f.ticks
+		case f.fence == f.ticks:
+			f.fence = 0
+		}
+		f.ticks = 0
+	}
+
+	if r == ' ' || r == '\t' {
+		if !f.dropSpace {
+			f.space.WriteRune(r)
+		}
+		return
+	}
+	f.dropSpace = false
+
+	if r == '[' && f.fence == 0 {
+		f.pending.WriteRune(r)
+		return
+	}
+
+	f.emit(out, f.takeSpace()+string(r))
+}
+
+// drop выбрасывает вырезанный маркер. Пробел перед ним уходит вместе с маркером: разделителем
+// служит пробел после маркера. В начале строки разделять нечего, поэтому пропускается пробел после
+func (f *MarkerFilter) drop() {
+	if f.space.Len() == 0 && (f.prev == 0 || f.prev == '\n') {
+		f.dropSpace = true
+	}
+	f.space.Reset()
+}
+
+func (f *MarkerFilter) takeSpace() string {
+	space := f.space.String()
+	f.space.Reset()
+	return space
+}
+
+// resolve оставляет в списке маркера только существующие фрагменты.
+// Если не осталось ни одного, маркер вырезается целиком
+func (f *MarkerFilter) resolve(content string) string {
+	if !markerListPattern.MatchString(content) {
+		return "[" + content + "]"
+	}
+
+	valid := make([]string, 0)
+	for _, part := range strings.Split(content, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || n < 1 || n > f.fragments {
+			continue
+		}
+		if !f.seen[n] {
+			f.seen[n] = true
+			f.cited = append(f.cited, n)
+		}
+		valid = append(valid, strconv.Itoa(n))
+	}
+
+	if len(valid) == 0 {
+		return ""
+	}
+	return "[" + strings.Join(valid, ", ") + "]"
+}
+
+func (f *MarkerFilter) emit(out *strings.Builder, text string) {
+	if text == "" {
+		return
+	}
+	out.WriteString(text)
+	for _, r := range text {
+		f.prev = r
+	}
+}
@@ -0,0 +1,78 @@
+package rag
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// filterChunks прогоняет через фильтр текст, разрезанный на куски
+func filterChunks(fragments int, chunks []string) (string, []int) {
+	f := NewMarkerFilter(fragments)
+	var out strings.Builder
+	for _, chunk := range chunks {
+		out.WriteString(f.Write(chunk))
+	}
+	out.WriteString(f.Flush())
+	return out.String(), f.Cited()
+}
+
+func TestMarkerFilter(t *testing.T) {
+	tests := []struct {
+		name      string
+		fragments int
+		input     string
+		want      string
+		wantCited []int
+	}{
+		{name: "valid marker", fragments: 3, input: "Ответ [2] здесь.", want: "Ответ [2] здесь.", wantCited: []int{2}},
+		{name: "invalid marker between words", fragments: 3, input: "и [5] и", want: "и и"},
+		{name: "invalid marker before punctuation", fragments: 3, input: "слово [5].", want: "слово."},
+		{name: "invalid marker at end", fragments: 3, input: "конец [9]", want: "конец"},
+		{name: "invalid marker at line start", fragments: 3, input: "[7] Текст\n[8] ещё", want: "Текст\nещё"},
+		{name: "valid marker after word", fragments: 3, input: "слово[3] и", want: "слово[3] и", wantCited: []int{3}},
+		{name: "invalid marker after word", fragments: 3, input: "слово[7] и", want: "слово и"},
+		{name: "adjacent invalid markers", fragments: 2, input: "и [5][6] и", want: "и и"},
+		{name: "invalid between valid", fragments: 2, input: "a [1] [5] [2] b", want: "a [1] [2] b", wantCited: []int{1, 2}},
+		{name: "list keeps valid numbers", fragments: 3, input: "факт [1, 4,3].", want: "факт [1, 3].", wantCited: []int{1, 3}},
+		{name: "cited in order of first mention", fragments: 3, input: "[3] и [1] и [3]", want: "[3] и [1] и [3]", wantCited: []int{3, 1}},
+		{name: "zero is invalid", fragments: 3, input: "x [0] y", want: "x y"},
+		{name: "not a marker", fragments: 3, input: "ссылка [текст](url) и [a1]", want: "ссылка [текст](url) и [a1]"},
+		{name: "unclosed bracket", fragments: 3, input: "хвост [12", want: "хвост [12"},
+		{name: "overlong bracket", fragments: 3, input: "[" + strings.Repeat("1", 30) + "]", want: "[" + strings.Repeat("1", 30) + "]"},
+		{name: "inline code untouched", fragments: 1, input: "код `arr[9]` и [9] тут", want: "код `arr[9]` и тут"},
+		{name: "fenced code untouched", fragments: 1, input: "```\nx = a[5]\n```\nтекст [5]", want: "```\nx = a[5]\n```\nтекст"},
+		{name: "spaces kept without markers", fragments: 1, input: "два  пробела\tи таб ", want: "два  пробела\tи таб "},
+		{name: "no fragments", fragments: 0, input: "всё [1] вырезано [2].", want: "всё вырезано."},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			splits := map[string][]string{"whole": {tt.input}}
+
+			runes := []rune(tt.input)
+			perRune := make([]string, len(runes))
+			for i, r := range runes {
+				perRune[i] = string(r)
+			}
+			splits["per rune"] = perRune
+
+			for i := 1; i < len(runes); i++ {
+				splits["split at "+string(runes[:i])] = []string{string(runes[:i]), string(runes[i:])}
+			}
+
+			for name, chunks := range splits {
+				got, cited := filterChunks(tt.fragments, chunks)
+				if got != tt.want {
+					t.Errorf("%s: got %q, want %q", name, got, tt.want)
+				}
+				if len(cited) == 0 && len(tt.wantCited) == 0 {
+					continue
+				}
+				if !reflect.DeepEqual(cited, tt.wantCited) {
+					t.Errorf("%s: cited %v, want %v", name, cited, tt.wantCited)
+				}
+			}
+		})
+	}
+}
@@ -13,7 +13,10 @@ const SystemPrompt = "Ты ассистент для ответов на воп
 	"укажи на это явно, не придумывай недостающие детали.\n" +
 	"3. Цитируй факты из контекста напрямую, не перефразируй если не уверен.\n" +
 	"4. Если вопрос частично покрыт контекстом - ответь только на ту часть, которая есть, " +
-	"и укажи что остальное отсутствует.\n\n" +
+	"и укажи что остальное отсутствует.\n" +
+	"5. После каждого утверждения ставь ссылку на источник в квадратных скобках: [n], где n - номер фрагмента " +
+	"из заголовка «Фрагмент n». Несколько источников указывай через запятую: [1, 3]. " +
+	"Не ссылайся на фрагменты, которых нет в контексте.\n\n" +
 	"НИКОГДА не додумывай, не предполагай, не обобщай за пределами данного контекста."
 
 func BuildUserPrompt(question string, chunks []string) string {
@@ -23,6 +26,27 @@ func BuildUserPrompt(question string, chunks []string) string {
 		b.WriteString(fmt.Sprintf("--- Фрагмент %d ---\n%s\n\n", i+1, chunk))
 	}
 	b.WriteString("</context>\n\n")
-	b.WriteString(fmt.Sprintf("Вопрос: %s", question))
+	b.WriteString(fmt.Sprintf("Вопрос: %s\n\n", question))
+	b.WriteString("Отмечай каждое утверждение ответа маркером [n] с номером фрагмента, на котором оно основано.")
+	return b.String()
+}
+
+const CondensePrompt = "Перепиши последний вопрос пользователя так, чтобы он был понятен без истории диалога. " +
+	"Раскрой местоимения и отсылки к предыдущим вопросам и ответам, сохрани термины, формулы и имена переменных. " +
+	"Не отвечай на вопрос. Выведи только переформулированный вопрос одной строкой."
+
+type HistoryTurn struct {
+	Question string
+	Answer   string
+}
+
+func BuildCondensePrompt(history []HistoryTurn, question string) string {
+	var b strings.Builder
+	b.WriteString("<history>\n")
+	for _, turn := range history {
+		b.WriteString(fmt.Sprintf("Вопрос: %s\nОтвет: %s\n\n", turn.Question, turn.Answer))
+	}
+	b.WriteString("</history>\n\n")
+	b.WriteString(fmt.Sprintf("Последний вопрос: %s", question))
 	return b.String()
 }
@@ -0,0 +1,192 @@
+package rag
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	DefaultChunkMaxTokens     = 512
+	DefaultChunkOverlapTokens = 64
+
+	// charsPerToken — грубая оценка длины токена в символах для bge-m3
+	charsPerToken = 4
+)
+
+type ChunkOptions struct {
+	MaxTokens     int
+	OverlapTokens int
+}
+
+// normalize подставляет значения по умолчанию и не даёт перекрытию съесть весь чанк
+func (o ChunkOptions) normalize() ChunkOptions {
+	if o.MaxTokens <= 0 {
+		o.MaxTokens = DefaultChunkMaxTokens
+	}
+	if o.OverlapTokens < 0 {
+		o.OverlapTokens = 0
+	}
+	if o.OverlapTokens > o.MaxTokens/2 {
+		o.OverlapTokens = o.MaxTokens / 2
+	}
+	return o
+}
+
+// EstimateTokens оценивает количество токенов без токенизатора:
+// каждое слово стоит минимум один токен, длинные слова — по токену на charsPerToken символов
+func EstimateTokens(text string) int {
+	tokens := 0
+	for _, word := range strings.Fields(text) {
+		tokens += (utf8.RuneCountInString(word) + charsPerToken - 1) / charsPerToken
+	}
+	return tokens
+}
+
+type span struct {
+	start int
+	end   int
+}
+
+type boundarySplitter struct {
+	pattern *regexp.Regexp
+	// before — граница проходит перед совпадением (заголовки), иначе после него (разделители)
+	before bool
+}
+
+// splitters упорядочены от крупных границ к мелким: H3, абзацы, строки, предложения, слова
+var splitters = []boundarySplitter{
+	{pattern: regexp.MustCompile(`(?m)^###\s+`), before: true},
+	{pattern: regexp.MustCompile(`\n[ \t]*\n\s*`)},
+	{pattern: regexp.MustCompile(`\n`)},
+	{pattern: regexp.MustCompile(`[.!?…]+["»)]*\s+`)},
+	{pattern: regexp.MustCompile(`\s+`)},
+}
+
+// splitText делит text на фрагменты не длиннее budget токенов с перекрытием overlap токенов.
+// Фрагменты возвращаются как отрезки исходного текста, поэтому разделители внутри чанка сохраняются
+func splitText(text string, budget, overlap int) []span {
+	full := trimSpan(text, span{start: 0, end: len(text)})
+	if full.start >= full.end {
+		return nil
+	}
+
+	pieces := splitRecursive(text, full, budget, 0)
+	return mergePieces(text, pieces, budget, overlap)
+}
+
+func splitRecursive(text string, sp span, budget, level int) []span {
+	if EstimateTokens(text[sp.start:sp.end]) <= budget {
+		return []span{sp}
+	}
+
+	if level >= len(splitters) {
+		return hardSplit(text, sp, budget)
+	}
+
+	parts := splitters[level].split(text, sp)
+	if len(parts) <= 1 {
+		return splitRecursive(text, sp, budget, level+1)
+	}
+
+	result := make([]span, 0, len(parts))
+	for _, part := range parts {
+		result = append(result, splitRecursive(text, part, budget, level+1)...)
+	}
+	return result
+}
+
+func (s boundarySplitter) split(text string, sp span) []span {
+	matches := s.pattern.FindAllStringIndex(text[sp.start:sp.end], -1)
+
+	parts := make([]span, 0, len(matches)+1)
+	cur := sp.start
+	for _, m := range matches {
+		boundary := sp.start + m[1]
+		if s.before {
+			boundary = sp.start + m[0]
+		}
+		if boundary <= cur {
+			continue
+		}
+		if part := trimSpan(text, span{start: cur, end: boundary}); part.start < part.end {
+			parts = append(parts, part)
+		}
+		cur = boundary
+	}
+	if part := trimSpan(text, span{start: cur, end: sp.end}); part.start < part.end {
+		parts = append(parts, part)
+	}
+
+	return parts
+}
+
+// hardSplit режет по символам фрагмент без пробелов, который не помещается в budget
+func hardSplit(text string, sp span, budget int) []span {
+	limit := max(budget*charsPerToken, 1)
+
+	result := make([]span, 0)
+	start := sp.start
+	runes := 0
+	for i := range text[sp.start:sp.end] {
+		if runes == limit {
+			result = append(result, span{start: start, end: sp.start + i})
+			start = sp.start + i
+			runes = 0
+		}
+		runes++
+	}
+	if start < sp.end {
+		result = append(result, span{start: start, end: sp.end})
+	}
+	return result
+}
+
+// mergePieces жадно собирает соседние фрагменты в чанки до budget токенов.
+// Новый чанк начинается с хвостовых фрагментов предыдущего, укладывающихся в overlap
+func mergePieces(text string, pieces []span, budget, overlap int) []span {
+	tokens := func(from, to int) int {
+		return EstimateTokens(text[pieces[from].start:pieces[to].end])
+	}
+
+	result := make([]span, 0)
+	first := 0
+	for first < len(pieces) {
+		last := first
+		for last+1 < len(pieces) && tokens(first, last+1) <= budget {
+			last++
+		}
+		result = append(result, span{start: pieces[first].start, end: pieces[last].end})
+
+		if last == len(pieces)-1 {
+			break
+		}
+
+		next := last + 1
+		for next-1 > first && tokens(next-1, last) <= overlap && tokens(next-1, last+1) <= budget {
+			next--
+		}
+		first = next
+	}
+
+	return result
+}
+
+func trimSpan(text string, sp span) span {
+	for sp.start < sp.end {
+		r, size := utf8.DecodeRuneInString(text[sp.start:sp.end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		sp.start += size
+	}
+	for sp.end > sp.start {
+		r, size := utf8.DecodeLastRuneInString(text[sp.start:sp.end])
+		if !unicode.IsSpace(r) {
+			break
+		}
+		sp.end -= size
+	}
+	return sp
+}
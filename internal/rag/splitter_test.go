@@ -0,0 +1,179 @@
+package rag
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+// checkChunks проверяет общие инварианты чанков: непустой текст, бюджет токенов
+// и смещения, указывающие на фрагмент исходного текста внутри чанка
+func checkChunks(t *testing.T, text string, docs []Document, maxTokens int) {
+	t.Helper()
+
+	runes := []rune(text)
+	for i, doc := range docs {
+		if doc.Metadata.ChunkID != i {
+			t.Errorf("chunk %d has id %d", i, doc.Metadata.ChunkID)
+		}
+		if strings.TrimSpace(doc.PageContent) == "" {
+			t.Errorf("chunk %d is empty", i)
+		}
+		if tokens := EstimateTokens(doc.PageContent); tokens > maxTokens {
+			t.Errorf("chunk %d has %d tokens, limit %d", i, tokens, maxTokens)
+		}
+
+		start, end := doc.Metadata.StartOffset, doc.Metadata.EndOffset
+		if start < 0 || start >= end || end > len(runes) {
+			t.Errorf("chunk %d has invalid offsets [%d, %d) for text of %d runes", i, start, end, len(runes))
+			continue
+		}
+		if fragment := string(runes[start:end]); !strings.Contains(doc.PageContent, fragment) {
+			t.Errorf("chunk %d does not contain its source fragment %q", i, fragment)
+		}
+	}
+}
+
+func TestEstimateTokens(t *testing.T) {
+	tests := []struct {
+		text string
+		want int
+	}{
+		{"", 0},
+		{"   ", 0},
+		{"a bb ccc dddd", 4},
+		{"abcde", 2},
+		{"привет мир", 3},
+	}
+
+	for _, tt := range tests {
+		if got := EstimateTokens(tt.text); got != tt.want {
+			t.Errorf("EstimateTokens(%q) = %d, want %d", tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestChunkStudentMarkdownSmallSections(t *testing.T) {
+	text := "# Отчёт\n\nВступление.\n\n## Цель\n\nНайти ответ.\n\n## Итог\n\nОтвет найден."
+	docs := ChunkStudentMarkdown(text, "student", "assignment", 2, "report.md", ChunkOptions{MaxTokens: 100})
+
+	wantTitles := []string{"Введение", "Цель", "Итог"}
+	if len(docs) != len(wantTitles) {
+		t.Fatalf("got %d chunks, want %d", len(docs), len(wantTitles))
+	}
+	for i, doc := range docs {
+		if doc.Metadata.SectionTitle != wantTitles[i] {
+			t.Errorf("chunk %d section %q, want %q", i, doc.Metadata.SectionTitle, wantTitles[i])
+		}
+		if doc.Metadata.DocumentTitle != "Отчёт" || doc.Metadata.Version != 2 || doc.Metadata.SourceName != "report.md" {
+			t.Errorf("chunk %d has wrong metadata: %+v", i, doc.Metadata)
+		}
+	}
+	if !strings.HasPrefix(docs[1].PageContent, "## Цель\n\n") {
+		t.Errorf("chunk does not start with its heading: %q", docs[1].PageContent)
+	}
+	checkChunks(t, text, docs, 100)
+}
+
+func TestChunkStudentMarkdownSplitsLargeSections(t *testing.T) {
+	var b strings.Builder
+	b.WriteString("# Документ\n\n## Большой раздел\n\n")
+	for i := 0; i < 40; i++ {
+		fmt.Fprintf(&b, "Предложение номер %d описывает ещё одну деталь работы. ", i)
+		if i%5 == 4 {
+			b.WriteString("\n\n")
+		}
+	}
+	b.WriteString("\n\n### Подраздел\n\nКороткий текст.\n\n## Маленький\n\nСовсем мало.")
+	text := b.String()
+
+	opts := ChunkOptions{MaxTokens: 60, OverlapTokens: 10}
+	docs := ChunkStudentMarkdown(text, "s", "a", 1, "", opts)
+	if len(docs) < 3 {
+		t.Fatalf("got %d chunks, want the large section split", len(docs))
+	}
+	for _, doc := range docs[:len(docs)-1] {
+		if !strings.HasPrefix(doc.PageContent, "## Большой раздел\n\n") {
+			t.Errorf("subchunk lost its section heading: %q", doc.PageContent[:min(len(doc.PageContent), 40)])
+		}
+	}
+	checkChunks(t, text, docs, opts.MaxTokens)
+}
+
+func TestChunkStudentMarkdownWithoutHeaders(t *testing.T) {
+	text := "\n\n  Просто текст без заголовков.\nВторая строка.  \n"
+	docs := ChunkStudentMarkdown(text, "s", "a", 1, "", ChunkOptions{})
+
+	if len(docs) != 1 {
+		t.Fatalf("got %d chunks, want 1", len(docs))
+	}
+	if docs[0].Metadata.SectionTitle != "Без заголовка" {
+		t.Errorf("section %q, want «Без заголовка»", docs[0].Metadata.SectionTitle)
+	}
+	if docs[0].PageContent != strings.TrimSpace(text) {
+		t.Errorf("content %q", docs[0].PageContent)
+	}
+	checkChunks(t, text, docs, DefaultChunkMaxTokens)
+}
+
+func TestChunkStudentMarkdownEmpty(t *testing.T) {
+	for _, text := range []string{"", "   \n\n\t", "## Пустой раздел\n\n## Ещё один\n"} {
+		if docs := ChunkStudentMarkdown(text, "s", "a", 1, "", ChunkOptions{}); len(docs) != 0 {
+			t.Errorf("ChunkStudentMarkdown(%q) returned %d chunks, want none", text, len(docs))
+		}
+	}
+}
+
+func TestSplitTextOverlap(t *testing.T) {
+	words := make([]string, 100)
+	for i := range words {
+		words[i] = fmt.Sprintf("w%02d", i)
+	}
+	text := strings.Join(words, " ")
+
+	spans := splitText(text, 20, 5)
+	if len(spans) < 5 {
+		t.Fatalf("got %d spans, want at least 5", len(spans))
+	}
+	for i, sp := range spans {
+		if tokens := EstimateTokens(text[sp.start:sp.end]); tokens > 20 {
+			t.Errorf("span %d has %d tokens", i, tokens)
+		}
+		if i == 0 {
+			continue
+		}
+		prev := spans[i-1]
+		if sp.start >= prev.end {
+			t.Errorf("span %d does not overlap the previous one", i)
+		}
+		if overlap := EstimateTokens(text[sp.start:prev.end]); overlap > 5 {
+			t.Errorf("span %d overlaps by %d tokens, limit 5", i, overlap)
+		}
+		if sp.start <= prev.start {
+			t.Errorf("span %d does not advance", i)
+		}
+	}
+	if last := spans[len(spans)-1]; last.end != len(text) {
+		t.Errorf("text tail is lost: last span ends at %d of %d", last.end, len(text))
+	}
+}
+
+func TestSplitTextLongWord(t *testing.T) {
+	text := strings.Repeat("ж", 100)
+	spans := splitText(text, 5, 0)
+
+	total := 0
+	for i, sp := range spans {
+		if n := utf8.RuneCountInString(text[sp.start:sp.end]); n > 5*charsPerToken {
+			t.Errorf("span %d has %d runes", i, n)
+		}
+		if !utf8.ValidString(text[sp.start:sp.end]) {
+			t.Errorf("span %d cuts a rune", i)
+		}
+		total += sp.end - sp.start
+	}
+	if total != len(text) {
+		t.Errorf("spans cover %d bytes of %d", total, len(text))
+	}
+}
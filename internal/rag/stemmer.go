@@ -0,0 +1,197 @@
+package rag
+
+import (
+	"sort"
+	"strings"
+)
+
+// Стеммер для русского языка по алгоритму Snowball (Портер).
+// Окончания ищутся только в области RV, словообразовательные суффиксы — в R2
+
+type endingGroup struct {
+	// afterAOrYa — окончание группы 1: отрезается, только если перед ним стоит «а» или «я»
+	afterAOrYa []string
+	plain      []string
+}
+
+var (
+	perfectiveGerund = endingGroup{
+		afterAOrYa: []string{"в", "вши", "вшись"},
+		plain:      []string{"ив", "ивши", "ившись", "ыв", "ывши", "ывшись"},
+	}
+	adjective = endingGroup{
+		plain: []string{
+			"ее", "ие", "ые", "ое", "ими", "ыми", "ей", "ий", "ый", "ой", "ем", "им", "ым", "ом",
+			"его", "ого", "ему", "ому", "их", "ых", "ую", "юю", "ая", "яя", "ою", "ею",
+		},
+	}
+	participle = endingGroup{
+		afterAOrYa: []string{"ем", "нн", "вш", "ющ", "щ"},
+		plain:      []string{"ивш", "ывш", "ующ"},
+	}
+	reflexive = endingGroup{
+		plain: []string{"ся", "сь"},
+	}
+	verb = endingGroup{
+		afterAOrYa: []string{"ла", "на", "ете", "йте", "ли", "й", "л", "ем", "н", "ло", "но", "ет", "ют", "ны", "ть", "ешь", "нно"},
+		plain: []string{
+			"ила", "ыла", "ена", "ейте", "уйте", "ите", "или", "ыли", "ей", "уй", "ил", "ыл", "им", "ым", "ен",
+			"ило", "ыло", "ено", "ят", "ует", "уют", "ит", "ыт", "ены", "ить", "ыть", "ишь", "ую", "ю",
+		},
+	}
+	noun = endingGroup{
+		plain: []string{
+			"а", "ев", "ов", "ие", "ье", "е", "иями", "ями", "ами", "еи", "ии", "и", "ией", "ей", "ой", "ий", "й",
+			"иям", "ям", "ием", "ем", "ам", "ом", "о", "у", "ах", "иях", "ях", "ы", "ь", "ию", "ью", "ю", "ия", "ья", "я",
+		},
+	}
+	superlative = endingGroup{
+		plain: []string{"ейш", "ейше"},
+	}
+	derivational = endingGroup{
+		plain: []string{"ост", "ость"},
+	}
+)
+
+func init() {
+	for _, g := range []*endingGroup{&perfectiveGerund, &adjective, &participle, &reflexive, &verb, &noun, &superlative, &derivational} {
+		sortByLengthDesc(g.afterAOrYa)
+		sortByLengthDesc(g.plain)
+	}
+}
+
+func sortByLengthDesc(endings []string) {
+	sort.SliceStable(endings, func(i, j int) bool {
+		return len([]rune(endings[i])) > len([]rune(endings[j]))
+	})
+}
+
+func isRussianVowel(r rune) bool {
+	return strings.ContainsRune("аеиоуыэюя", r)
+}
+
+// StemRussian возвращает основу русского слова. Слово должно быть в нижнем регистре
+func StemRussian(word string) string {
+	w := []rune(strings.ReplaceAll(word, "ё", "е"))
+
+	rv, r2 := russianRegions(w)
+	if rv >= len(w) {
+		return string(w)
+	}
+
+	// шаг 1
+	if stripped, ok := perfectiveGerund.strip(w, rv); ok {
+		w = stripped
+	} else {
+		if stripped, ok := reflexive.strip(w, rv); ok {
+			w = stripped
+		}
+		if stripped, ok := stripAdjectival(w, rv); ok {
+			w = stripped
+		} else if stripped, ok := verb.strip(w, rv); ok {
+			w = stripped
+		} else if stripped, ok := noun.strip(w, rv); ok {
+			w = stripped
+		}
+	}
+
+	// шаг 2
+	if len(w) > rv && w[len(w)-1] == 'и' {
+		w = w[:len(w)-1]
+	}
+
+	// шаг 3
+	if stripped, ok := derivational.strip(w, max(r2, rv)); ok {
+		w = stripped
+	}
+
+	// шаг 4
+	if stripped, ok := superlative.strip(w, rv); ok {
+		w = stripped
+	}
+	switch {
+	case len(w)-2 >= rv && w[len(w)-1] == 'н' && w[len(w)-2] == 'н':
+		w = w[:len(w)-1]
+	case len(w) > rv && w[len(w)-1] == 'ь':
+		w = w[:len(w)-1]
+	}
+
+	return string(w)
+}
+
+// russianRegions вычисляет начало областей RV и R2
+func russianRegions(w []rune) (int, int) {
+	rv := len(w)
+	for i, r := range w {
+		if isRussianVowel(r) {
+			rv = i + 1
+			break
+		}
+	}
+
+	after := func(start int) int {
+		for i := start + 1; i < len(w); i++ {
+			if !isRussianVowel(w[i]) && isRussianVowel(w[i-1]) {
+				return i + 1
+			}
+		}
+		return len(w)
+	}
+
+	r1 := after(0)
+	r2 := after(r1)
+	if r1 >= len(w) {
+		r2 = len(w)
+	}
+
+	return rv, r2
+}
+
+func (g endingGroup) strip(w []rune, limit int) ([]rune, bool) {
+	best := -1
+	for _, ending := range g.afterAOrYa {
+		e := []rune(ending)
+		pos := len(w) - len(e)
+		if pos-1 >= limit && hasSuffix(w, e) && (w[pos-1] == 'а' || w[pos-1] == 'я') {
+			best = max(best, len(e))
+			break
+		}
+	}
+	for _, ending := range g.plain {
+		e := []rune(ending)
+		if len(w)-len(e) >= limit && hasSuffix(w, e) {
+			best = max(best, len(e))
+			break
+		}
+	}
+
+	if best < 0 {
+		return w, false
+	}
+	return w[:len(w)-best], true
+}
+
+// stripAdjectival отрезает окончание прилагательного и, если есть, суффикс причастия перед ним
+func stripAdjectival(w []rune, rv int) ([]rune, bool) {
+	stripped, ok := adjective.strip(w, rv)
+	if !ok {
+		return w, false
+	}
+	if withoutParticiple, ok := participle.strip(stripped, rv); ok {
+		return withoutParticiple, true
+	}
+	return stripped, true
+}
+
+func hasSuffix(w, suffix []rune) bool {
+	if len(suffix) > len(w) {
+		return false
+	}
+	offset := len(w) - len(suffix)
+	for i, r := range suffix {
+		if w[offset+i] != r {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,47 @@
+package rag
+
+import "testing"
+
+func TestStemRussian(t *testing.T) {
+	// ожидаемые основы совпадают с эталонной реализацией Snowball
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"книги", "книг"},
+		{"книгами", "книг"},
+		{"столами", "стол"},
+		{"вечерами", "вечер"},
+		{"солнце", "солнц"},
+		{"жизнь", "жизн"},
+		{"важная", "важн"},
+		{"важнейший", "важн"},
+		{"красивый", "красив"},
+		{"красивая", "красив"},
+		{"хорошего", "хорош"},
+		{"вечернее", "вечерн"},
+		{"выбранный", "выбра"},
+		{"думающий", "дума"},
+		{"бегать", "бега"},
+		{"играли", "игра"},
+		{"стали", "стал"},
+		{"прочитав", "прочита"},
+		{"программирование", "программирован"},
+		{"общество", "обществ"},
+		{"действительность", "действительн"},
+		{"нежность", "нежност"},
+		{"быстро", "быстр"},
+		{"ежедневно", "ежедневн"},
+		{"ёлка", "елк"},
+		{"он", "он"},
+		{"в", "в"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.word, func(t *testing.T) {
+			if got := StemRussian(tt.word); got != tt.want {
+				t.Errorf("StemRussian(%q) = %q, want %q", tt.word, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	qdrantpkg "github.com/anton1ks96/college-core-api/pkg/database/qdrant"
+	"github.com/google/uuid"
+	"github.com/qdrant/go-client/qdrant"
+)
+
+// AnswerCacheQdrantRepository хранит готовые ответы в отдельной коллекции Qdrant,
+// вектором точки служит эмбеддинг вопроса
+type AnswerCacheQdrantRepository struct {
+	client     *qdrant.Client
+	collection string
+}
+
+func NewAnswerCacheRepository(cfg *config.Config, q *qdrantpkg.Qdrant) *AnswerCacheQdrantRepository {
+	return &AnswerCacheQdrantRepository{
+		client:     q.Client,
+		collection: cfg.Qdrant.Collection + "_answers",
+	}
+}
+
+func (r *AnswerCacheQdrantRepository) EnsureCollection(ctx context.Context, vectorSize uint64) error {
+	exists, err := r.client.CollectionExists(ctx, r.collection)
+	if err != nil {
+		return fmt.Errorf("failed to check answer cache collection existence: %w", err)
+	}
+
+	if !exists {
+		err = r.client.CreateCollection(ctx, &qdrant.CreateCollection{
+			CollectionName: r.collection,
+			VectorsConfig: qdrant.NewVectorsConfig(&qdrant.VectorParams{
+				Size:     vectorSize,
+				Distance: qdrant.Distance_Cosine,
+			}),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to create answer cache collection: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// Lookup ищет ближайший сохранённый вопрос к той же версии того же индекса.
+// Возвращает nil, nil, если похожих вопросов с косинусной близостью не ниже threshold нет
+func (r *AnswerCacheQdrantRepository) Lookup(
+	ctx context.Context,
+	datasetID string,
+	version int,
+	indexedAt time.Time,
+	questionVector []float32,
+	threshold float32,
+	notBefore time.Time,
+) (*domain.CachedAnswer, error) {
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch("dataset_id", datasetID),
+			qdrant.NewMatchInt("version", int64(version)),
+			qdrant.NewMatchInt("indexed_at", indexedAt.UnixNano()),
+			qdrant.NewRange("created_at", &qdrant.Range{Gte: qdrant.PtrOf(float64(notBefore.Unix()))}),
+		},
+	}
+
+	scored, err := r.client.Query(ctx, &qdrant.QueryPoints{
+		CollectionName: r.collection,
+		Query:          qdrant.NewQueryDense(questionVector),
+		Filter:         filter,
+		Limit:          qdrant.PtrOf(uint64(1)),
+		ScoreThreshold: qdrant.PtrOf(threshold),
+		WithPayload:    qdrant.NewWithPayload(true),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to search answer cache: %w", err)
+	}
+
+	if len(scored) == 0 {
+		return nil, nil
+	}
+
+	payload := scored[0].Payload
+	answer := &domain.CachedAnswer{
+		DatasetID: datasetID,
+		Version:   version,
+		Score:     scored[0].Score,
+	}
+	if v, ok := payload["question"]; ok {
+		answer.Question = v.GetStringValue()
+	}
+	if v, ok := payload["answer"]; ok {
+		answer.Answer = v.GetStringValue()
+	}
+	if v, ok := payload["citations"]; ok {
+		if err := json.Unmarshal([]byte(v.GetStringValue()), &answer.Citations); err != nil {
+			return nil, fmt.Errorf("failed to decode cached citations: %w", err)
+		}
+	}
+	if v, ok := payload["created_at"]; ok {
+		answer.CreatedAt = time.Unix(v.GetIntegerValue(), 0)
+	}
+
+	return answer, nil
+}
+
+func (r *AnswerCacheQdrantRepository) Store(ctx context.Context, answer *domain.CachedAnswer, indexedAt time.Time, questionVector []float32) error {
+	citations, err := json.Marshal(answer.Citations)
+	if err != nil {
+		return fmt.Errorf("failed to encode citations: %w", err)
+	}
+
+	answer.CreatedAt = time.Now()
+
+	_, err = r.client.Upsert(ctx, &qdrant.UpsertPoints{
+		CollectionName: r.collection,
+		Points: []*qdrant.PointStruct{{
+			Id:      qdrant.NewID(uuid.NewString()),
+			Vectors: qdrant.NewVectorsDense(questionVector),
+			Payload: qdrant.NewValueMap(map[string]any{
+				"dataset_id": answer.DatasetID,
+				"version":    answer.Version,
+				"indexed_at": indexedAt.UnixNano(),
+				"question":   answer.Question,
+				"answer":     answer.Answer,
+				"citations":  string(citations),
+				"created_at": answer.CreatedAt.Unix(),
+			}),
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to store cached answer: %w", err)
+	}
+
+	return nil
+}
+
+func (r *AnswerCacheQdrantRepository) DeleteByDatasetID(ctx context.Context, datasetID string) error {
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch("dataset_id", datasetID),
+		},
+	}
+
+	_, err := r.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: r.collection,
+		Points:         qdrant.NewPointsSelectorFilter(filter),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete cached answers for dataset %s: %w", datasetID, err)
+	}
+
+	return nil
+}
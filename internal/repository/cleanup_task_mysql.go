@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+type CleanupTaskMySQLRepository struct {
+	db  *sqlx.DB
+	cfg *config.Config
+}
+
+func NewCleanupTaskRepository(cfg *config.Config, db *sqlx.DB) *CleanupTaskMySQLRepository {
+	return &CleanupTaskMySQLRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+const cleanupTaskColumns = `id, dataset_id, kind, target, attempts, last_error, run_after, created_at, updated_at`
+
+// ClaimNext забирает ближайшую готовую задачу и откладывает её на lease: если процесс упадёт
+// посреди очистки, задача снова станет доступна по истечении lease. Возвращает nil, nil если задач нет
+func (r *CleanupTaskMySQLRepository) ClaimNext(ctx context.Context, lease time.Duration) (*domain.CleanupTask, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var task domain.CleanupTask
+	query := `
+		SELECT ` + cleanupTaskColumns + `
+		FROM cleanup_tasks
+		WHERE run_after <= ?
+		ORDER BY run_after ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	now := time.Now()
+	err = tx.GetContext(ctx, &task, query, now)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Error(fmt.Errorf("failed to select next cleanup task: %w", err))
+		return nil, err
+	}
+
+	updateQuery := `
+		UPDATE cleanup_tasks
+		SET attempts = attempts + 1, run_after = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	runAfter := now.Add(lease)
+	if _, err := tx.ExecContext(ctx, updateQuery, runAfter, now, task.ID); err != nil {
+		logger.Error(fmt.Errorf("failed to claim cleanup task %s: %w", task.ID, err))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	task.Attempts++
+	task.RunAfter = runAfter
+	task.UpdatedAt = now
+
+	return &task, nil
+}
+
+// Complete удаляет выполненную задачу
+func (r *CleanupTaskMySQLRepository) Complete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM cleanup_tasks WHERE id = ?`, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to complete cleanup task %s: %w", id, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *CleanupTaskMySQLRepository) MarkFailed(ctx context.Context, id, lastError string, retryAt time.Time) error {
+	query := `
+		UPDATE cleanup_tasks
+		SET last_error = ?, run_after = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, lastError, retryAt, time.Now(), id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to mark cleanup task %s as failed: %w", id, err))
+		return err
+	}
+
+	return nil
+}
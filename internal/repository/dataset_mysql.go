@@ -37,8 +37,8 @@ func (r *DatasetMySQLRepository) Create(ctx context.Context, dataset *domain.Dat
 	dataset.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO datasets (id, user_id, author, title, file_path, created_at, updated_at, topic_id, assignment_id)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO datasets (id, user_id, author, title, file_path, created_at, updated_at, topic_id, assignment_id, current_version)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
@@ -51,6 +51,7 @@ func (r *DatasetMySQLRepository) Create(ctx context.Context, dataset *domain.Dat
 		dataset.UpdatedAt,
 		dataset.TopicID,
 		dataset.AssignmentID,
+		dataset.CurrentVersion,
 	)
 
 	if err != nil {
@@ -65,9 +66,9 @@ func (r *DatasetMySQLRepository) Create(ctx context.Context, dataset *domain.Dat
 func (r *DatasetMySQLRepository) GetByID(ctx context.Context, id string) (*domain.Dataset, error) {
 	var dataset domain.Dataset
 	query := `
-		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
 		FROM datasets
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &dataset, query, id)
@@ -86,7 +87,7 @@ func (r *DatasetMySQLRepository) GetByUserID(ctx context.Context, userID string,
 	var datasets []domain.Dataset
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM datasets WHERE user_id = ?`
+	countQuery := `SELECT COUNT(*) FROM datasets WHERE user_id = ? AND deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery, userID)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count datasets for user %s: %w", userID, err))
@@ -94,9 +95,9 @@ func (r *DatasetMySQLRepository) GetByUserID(ctx context.Context, userID string,
 	}
 
 	query := `
-		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
 		FROM datasets
-		WHERE user_id = ?
+		WHERE user_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -119,7 +120,7 @@ func (r *DatasetMySQLRepository) GetByTeacherID(ctx context.Context, teacherID s
 		FROM datasets d
 		LEFT JOIN topic_assignments ta ON d.assignment_id = ta.id AND ta.assigned_by_id = ?
 		LEFT JOIN datasets_permission dp ON d.id = dp.dataset_id AND dp.teacher_id = ?
-		WHERE ta.id IS NOT NULL OR dp.id IS NOT NULL
+		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.deleted_at IS NULL
 	`
 	err := r.db.GetContext(ctx, &total, countQuery, teacherID, teacherID)
 	if err != nil {
@@ -128,11 +129,11 @@ func (r *DatasetMySQLRepository) GetByTeacherID(ctx context.Context, teacherID s
 	}
 
 	query := `
-		SELECT DISTINCT d.id, d.user_id, d.author, d.title, d.file_path, d.created_at, d.updated_at, d.indexed_at, d.topic_id, d.assignment_id, d.tag
+		SELECT DISTINCT d.id, d.user_id, d.author, d.title, d.file_path, d.created_at, d.updated_at, d.indexed_at, d.topic_id, d.assignment_id, d.tag, d.current_version
 		FROM datasets d
 		LEFT JOIN topic_assignments ta ON d.assignment_id = ta.id AND ta.assigned_by_id = ?
 		LEFT JOIN datasets_permission dp ON d.id = dp.dataset_id AND dp.teacher_id = ?
-		WHERE ta.id IS NOT NULL OR dp.id IS NOT NULL
+		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.deleted_at IS NULL
 		ORDER BY d.created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -150,7 +151,7 @@ func (r *DatasetMySQLRepository) GetAll(ctx context.Context, offset, limit int)
 	var datasets []domain.Dataset
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM datasets`
+	countQuery := `SELECT COUNT(*) FROM datasets WHERE deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count all datasets: %w", err))
@@ -158,8 +159,9 @@ func (r *DatasetMySQLRepository) GetAll(ctx context.Context, offset, limit int)
 	}
 
 	query := `
-		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
 		FROM datasets
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -178,13 +180,14 @@ func (r *DatasetMySQLRepository) Update(ctx context.Context, dataset *domain.Dat
 
 	query := `
 		UPDATE datasets
-		SET title = ?, file_path = ?, updated_at = ?
-		WHERE id = ?
+		SET title = ?, file_path = ?, current_version = ?, updated_at = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
 		dataset.Title,
 		dataset.FilePath,
+		dataset.CurrentVersion,
 		dataset.UpdatedAt,
 		dataset.ID,
 	)
@@ -229,6 +232,190 @@ func (r *DatasetMySQLRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
+// DeleteWithCleanup окончательно удаляет датасет из корзины и в той же транзакции ставит задачи очистки
+// объектов и векторов, чтобы удаление не потерялось при сбое между хранилищами.
+// Датасет, восстановленный после выборки просроченных, не удаляется и задач не получает
+func (r *DatasetMySQLRepository) DeleteWithCleanup(ctx context.Context, id string, tasks []domain.CleanupTask) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, `DELETE FROM datasets WHERE id = ? AND deleted_at IS NOT NULL`, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to delete dataset %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("item not found in trash")
+	}
+
+	insertQuery := `
+		INSERT INTO cleanup_tasks (id, dataset_id, kind, target, attempts, run_after, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	now := time.Now()
+	for i := range tasks {
+		taskID, err := uuid.NewV7()
+		if err != nil {
+			return fmt.Errorf("failed to generate UUID v7: %w", err)
+		}
+
+		task := &tasks[i]
+		task.ID = taskID.String()
+		task.DatasetID = id
+		task.Attempts = 0
+		task.RunAfter = now
+		task.CreatedAt = now
+		task.UpdatedAt = now
+
+		_, err = tx.ExecContext(ctx, insertQuery,
+			task.ID,
+			task.DatasetID,
+			task.Kind,
+			task.Target,
+			task.Attempts,
+			task.RunAfter,
+			task.CreatedAt,
+			task.UpdatedAt,
+		)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to create cleanup task for dataset %s: %w", id, err))
+			return fmt.Errorf("failed to create cleanup task: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s deleted, %d cleanup tasks queued", id, len(tasks)))
+	return nil
+}
+
+// SoftDelete переносит датасет в корзину. Версии, чаты и векторы остаются до окончательного удаления
+func (r *DatasetMySQLRepository) SoftDelete(ctx context.Context, id, deletedBy string) error {
+	query := `UPDATE datasets SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), deletedBy, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to soft delete dataset %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("dataset not found or already deleted")
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s moved to trash", id))
+	return nil
+}
+
+func (r *DatasetMySQLRepository) Restore(ctx context.Context, id string) error {
+	query := `UPDATE datasets SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to restore dataset %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("item not found in trash")
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s restored from trash", id))
+	return nil
+}
+
+// GetDeletedByID возвращает датасет из корзины
+func (r *DatasetMySQLRepository) GetDeletedByID(ctx context.Context, id string) (*domain.Dataset, error) {
+	var dataset domain.Dataset
+	query := `
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
+		FROM datasets
+		WHERE id = ? AND deleted_at IS NOT NULL
+	`
+
+	err := r.db.GetContext(ctx, &dataset, query, id)
+	if err != nil {
+		if errors.Is(sql.ErrNoRows, err) {
+			return nil, fmt.Errorf("item not found in trash")
+		}
+		logger.Error(fmt.Errorf("failed to get deleted dataset %s: %w", id, err))
+		return nil, err
+	}
+
+	return &dataset, nil
+}
+
+func (r *DatasetMySQLRepository) GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error) {
+	var items []domain.TrashItem
+	var total int
+
+	countQuery := `SELECT COUNT(*) FROM datasets WHERE deleted_at IS NOT NULL`
+	err := r.db.GetContext(ctx, &total, countQuery)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to count deleted datasets: %w", err))
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, title, COALESCE(author, user_id) AS owner, deleted_at, COALESCE(deleted_by, '') AS deleted_by
+		FROM datasets
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	err = r.db.SelectContext(ctx, &items, query, limit, offset)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get deleted datasets: %w", err))
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// GetDeletedBefore возвращает датасеты, пролежавшие в корзине дольше срока хранения
+func (r *DatasetMySQLRepository) GetDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Dataset, error) {
+	var datasets []domain.Dataset
+
+	query := `
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
+		FROM datasets
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+		ORDER BY deleted_at
+		LIMIT ?
+	`
+
+	err := r.db.SelectContext(ctx, &datasets, query, before, limit)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get expired deleted datasets: %w", err))
+		return nil, err
+	}
+
+	return datasets, nil
+}
+
 func (r *DatasetMySQLRepository) UpdateIndexedAt(ctx context.Context, id string) error {
 	now := time.Now()
 	query := `
@@ -247,12 +434,28 @@ func (r *DatasetMySQLRepository) UpdateIndexedAt(ctx context.Context, id string)
 	return nil
 }
 
+func (r *DatasetMySQLRepository) SetIndexedAt(ctx context.Context, id string, indexedAt *time.Time) error {
+	query := `
+		UPDATE datasets
+		SET indexed_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, indexedAt, time.Now(), id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to set indexed_at for dataset %s: %w", id, err))
+		return err
+	}
+
+	return nil
+}
+
 func (r *DatasetMySQLRepository) ExistsByUserIDAndTopicID(ctx context.Context, userID, topicID string) (bool, error) {
 	var count int
 	query := `
 		SELECT COUNT(*)
 		FROM datasets
-		WHERE user_id = ? AND topic_id = ?
+		WHERE user_id = ? AND topic_id = ? AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &count, query, userID, topicID)
@@ -264,8 +467,72 @@ func (r *DatasetMySQLRepository) ExistsByUserIDAndTopicID(ctx context.Context, u
 	return count > 0, nil
 }
 
+func (r *DatasetMySQLRepository) GetByTopicID(ctx context.Context, topicID string) ([]domain.Dataset, error) {
+	var datasets []domain.Dataset
+
+	query := `
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
+		FROM datasets
+		WHERE topic_id = ? AND deleted_at IS NULL
+	`
+
+	err := r.db.SelectContext(ctx, &datasets, query, topicID)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get datasets for topic %s: %w", topicID, err))
+		return nil, err
+	}
+
+	return datasets, nil
+}
+
+// GetIndexedByTopicIDAll возвращает до limit проиндексированных датасетов темы, новые первыми
+func (r *DatasetMySQLRepository) GetIndexedByTopicIDAll(ctx context.Context, topicID string, limit int) ([]domain.Dataset, error) {
+	var datasets []domain.Dataset
+
+	query := `
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
+		FROM datasets
+		WHERE topic_id = ? AND indexed_at IS NOT NULL AND deleted_at IS NULL
+		ORDER BY created_at DESC
+		LIMIT ?
+	`
+
+	err := r.db.SelectContext(ctx, &datasets, query, topicID, limit)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get indexed datasets for topic %s: %w", topicID, err))
+		return nil, err
+	}
+
+	return datasets, nil
+}
+
+// GetIndexedByTopicIDAndUserID — то же, но только датасеты, доступные пользователю:
+// свои, из заданий, выданных им как преподавателем, и открытые ему правами
+func (r *DatasetMySQLRepository) GetIndexedByTopicIDAndUserID(ctx context.Context, topicID, userID string, limit int) ([]domain.Dataset, error) {
+	var datasets []domain.Dataset
+
+	query := `
+		SELECT DISTINCT d.id, d.user_id, d.author, d.title, d.file_path, d.created_at, d.updated_at, d.indexed_at, d.topic_id, d.assignment_id, d.tag, d.current_version
+		FROM datasets d
+		LEFT JOIN topic_assignments ta ON d.assignment_id = ta.id AND ta.assigned_by_id = ?
+		LEFT JOIN datasets_permission dp ON d.id = dp.dataset_id AND dp.teacher_id = ?
+		WHERE (d.user_id = ? OR ta.id IS NOT NULL OR dp.id IS NOT NULL)
+			AND d.topic_id = ? AND d.indexed_at IS NOT NULL AND d.deleted_at IS NULL
+		ORDER BY d.created_at DESC
+		LIMIT ?
+	`
+
+	err := r.db.SelectContext(ctx, &datasets, query, userID, userID, userID, topicID, limit)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get indexed datasets for topic %s and user %s: %w", topicID, userID, err))
+		return nil, err
+	}
+
+	return datasets, nil
+}
+
 func (r *DatasetMySQLRepository) SetTag(ctx context.Context, id string, tag *string) error {
-	query := `UPDATE datasets SET tag = ?, updated_at = ? WHERE id = ?`
+	query := `UPDATE datasets SET tag = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
 
 	result, err := r.db.ExecContext(ctx, query, tag, time.Now(), id)
 	if err != nil {
@@ -289,7 +556,7 @@ func (r *DatasetMySQLRepository) GetByTagAll(ctx context.Context, tag string, of
 	var datasets []domain.Dataset
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM datasets WHERE tag = ?`
+	countQuery := `SELECT COUNT(*) FROM datasets WHERE tag = ? AND deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery, tag)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count datasets by tag %s: %w", tag, err))
@@ -297,9 +564,9 @@ func (r *DatasetMySQLRepository) GetByTagAll(ctx context.Context, tag string, of
 	}
 
 	query := `
-		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag
+		SELECT id, user_id, author, title, file_path, created_at, updated_at, indexed_at, topic_id, assignment_id, tag, current_version
 		FROM datasets
-		WHERE tag = ?
+		WHERE tag = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -322,7 +589,7 @@ func (r *DatasetMySQLRepository) GetByTagAndTeacherID(ctx context.Context, tag,
 		FROM datasets d
 		LEFT JOIN topic_assignments ta ON d.assignment_id = ta.id AND ta.assigned_by_id = ?
 		LEFT JOIN datasets_permission dp ON d.id = dp.dataset_id AND dp.teacher_id = ?
-		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.tag = ?
+		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.tag = ? AND d.deleted_at IS NULL
 	`
 	err := r.db.GetContext(ctx, &total, countQuery, teacherID, teacherID, tag)
 	if err != nil {
@@ -331,11 +598,11 @@ func (r *DatasetMySQLRepository) GetByTagAndTeacherID(ctx context.Context, tag,
 	}
 
 	query := `
-		SELECT DISTINCT d.id, d.user_id, d.author, d.title, d.file_path, d.created_at, d.updated_at, d.indexed_at, d.topic_id, d.assignment_id, d.tag
+		SELECT DISTINCT d.id, d.user_id, d.author, d.title, d.file_path, d.created_at, d.updated_at, d.indexed_at, d.topic_id, d.assignment_id, d.tag, d.current_version
 		FROM datasets d
 		LEFT JOIN topic_assignments ta ON d.assignment_id = ta.id AND ta.assigned_by_id = ?
 		LEFT JOIN datasets_permission dp ON d.id = dp.dataset_id AND dp.teacher_id = ?
-		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.tag = ?
+		WHERE (ta.id IS NOT NULL OR dp.id IS NOT NULL) AND d.tag = ? AND d.deleted_at IS NULL
 		ORDER BY d.created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -0,0 +1,203 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type DatasetVersionMySQLRepository struct {
+	db  *sqlx.DB
+	cfg *config.Config
+}
+
+func NewDatasetVersionRepository(cfg *config.Config, db *sqlx.DB) *DatasetVersionMySQLRepository {
+	return &DatasetVersionMySQLRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (r *DatasetVersionMySQLRepository) Create(ctx context.Context, version *domain.DatasetVersion) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID v7: %w", err)
+	}
+
+	version.ID = id.String()
+	version.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO dataset_versions (id, dataset_id, version, file_path, size, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		version.ID,
+		version.DatasetID,
+		version.Version,
+		version.FilePath,
+		version.Size,
+		version.CreatedBy,
+		version.CreatedAt,
+	)
+
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to create dataset version: %w", err))
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s version %d created", version.DatasetID, version.Version))
+	return nil
+}
+
+func (r *DatasetVersionMySQLRepository) GetByDatasetID(ctx context.Context, datasetID string) ([]domain.DatasetVersion, error) {
+	var versions []domain.DatasetVersion
+
+	query := `
+		SELECT id, dataset_id, version, file_path, size, created_by, created_at, indexed_at
+		FROM dataset_versions
+		WHERE dataset_id = ?
+		ORDER BY version DESC
+	`
+
+	err := r.db.SelectContext(ctx, &versions, query, datasetID)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get versions for dataset %s: %w", datasetID, err))
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+// GetAll возвращает версии всех датасетов, нужно для сверки хранилищ
+func (r *DatasetVersionMySQLRepository) GetAll(ctx context.Context) ([]domain.DatasetVersion, error) {
+	var versions []domain.DatasetVersion
+
+	query := `
+		SELECT id, dataset_id, version, file_path, size, created_by, created_at, indexed_at
+		FROM dataset_versions
+		ORDER BY dataset_id, version
+	`
+
+	err := r.db.SelectContext(ctx, &versions, query)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get dataset versions: %w", err))
+		return nil, err
+	}
+
+	return versions, nil
+}
+
+func (r *DatasetVersionMySQLRepository) GetByVersion(ctx context.Context, datasetID string, version int) (*domain.DatasetVersion, error) {
+	var v domain.DatasetVersion
+
+	query := `
+		SELECT id, dataset_id, version, file_path, size, created_by, created_at, indexed_at
+		FROM dataset_versions
+		WHERE dataset_id = ? AND version = ?
+	`
+
+	err := r.db.GetContext(ctx, &v, query, datasetID, version)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("version not found")
+		}
+		logger.Error(fmt.Errorf("failed to get version %d of dataset %s: %w", version, datasetID, err))
+		return nil, err
+	}
+
+	return &v, nil
+}
+
+// CreateNext выделяет следующий номер версии под блокировкой строки датасета и сохраняет версию.
+// store получает версию с уже выделенным номером и пишет объект до вставки строки, поэтому
+// версия становится видна только вместе с объектом. Параллельные вызовы для одного датасета
+// выполняются по очереди и не могут получить один номер
+func (r *DatasetVersionMySQLRepository) CreateNext(ctx context.Context, version *domain.DatasetVersion, store func(version *domain.DatasetVersion) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var datasetID string
+	lockQuery := `SELECT id FROM datasets WHERE id = ? AND deleted_at IS NULL FOR UPDATE`
+	if err := tx.GetContext(ctx, &datasetID, lockQuery, version.DatasetID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("dataset not found")
+		}
+		logger.Error(fmt.Errorf("failed to lock dataset %s: %w", version.DatasetID, err))
+		return err
+	}
+
+	var latest int
+	latestQuery := `SELECT COALESCE(MAX(version), 0) FROM dataset_versions WHERE dataset_id = ?`
+	if err := tx.GetContext(ctx, &latest, latestQuery, version.DatasetID); err != nil {
+		logger.Error(fmt.Errorf("failed to get latest version of dataset %s: %w", version.DatasetID, err))
+		return err
+	}
+
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID v7: %w", err)
+	}
+
+	version.ID = id.String()
+	version.Version = latest + 1
+	version.CreatedAt = time.Now()
+
+	if err := store(version); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO dataset_versions (id, dataset_id, version, file_path, size, created_by, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = tx.ExecContext(ctx, query,
+		version.ID,
+		version.DatasetID,
+		version.Version,
+		version.FilePath,
+		version.Size,
+		version.CreatedBy,
+		version.CreatedAt,
+	)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to create dataset version: %w", err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s version %d created", version.DatasetID, version.Version))
+	return nil
+}
+
+func (r *DatasetVersionMySQLRepository) UpdateIndexedAt(ctx context.Context, datasetID string, version int) error {
+	query := `
+		UPDATE dataset_versions
+		SET indexed_at = ?
+		WHERE dataset_id = ? AND version = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, time.Now(), datasetID, version)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to update indexed_at for dataset %s version %d: %w", datasetID, version, err))
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,156 @@
+package repository
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// embeddingCacheBatch ограничивает число хешей в одном IN, строк в одном INSERT и DELETE
+	embeddingCacheBatch = 500
+	// embeddingTouchInterval — last_used_at обновляется не чаще, чтобы чтение кэша не превращалось в запись
+	embeddingTouchInterval = 24 * time.Hour
+)
+
+type EmbeddingCacheMySQLRepository struct {
+	db  *sqlx.DB
+	cfg *config.Config
+}
+
+func NewEmbeddingCacheRepository(cfg *config.Config, db *sqlx.DB) *EmbeddingCacheMySQLRepository {
+	return &EmbeddingCacheMySQLRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (r *EmbeddingCacheMySQLRepository) GetEmbeddings(ctx context.Context, model string, hashes []string) (map[string][]float32, error) {
+	result := make(map[string][]float32, len(hashes))
+
+	for start := 0; start < len(hashes); start += embeddingCacheBatch {
+		end := min(start+embeddingCacheBatch, len(hashes))
+
+		query, args, err := sqlx.In(`
+			SELECT text_hash, vector
+			FROM embedding_cache
+			WHERE model = ? AND text_hash IN (?)
+		`, model, hashes[start:end])
+		if err != nil {
+			return nil, fmt.Errorf("failed to build embedding cache query: %w", err)
+		}
+
+		var rows []struct {
+			TextHash string `db:"text_hash"`
+			Vector   []byte `db:"vector"`
+		}
+		if err := r.db.SelectContext(ctx, &rows, r.db.Rebind(query), args...); err != nil {
+			logger.Error(fmt.Errorf("failed to get cached embeddings: %w", err))
+			return nil, err
+		}
+
+		hits := make([]string, 0, len(rows))
+		for _, row := range rows {
+			result[row.TextHash] = decodeVector(row.Vector)
+			hits = append(hits, row.TextHash)
+		}
+
+		if err := r.touch(ctx, model, hits); err != nil {
+			logger.Warn(fmt.Sprintf("failed to update embedding cache usage: %v", err))
+		}
+	}
+
+	return result, nil
+}
+
+// touch продлевает жизнь использованных записей
+func (r *EmbeddingCacheMySQLRepository) touch(ctx context.Context, model string, hashes []string) error {
+	if len(hashes) == 0 {
+		return nil
+	}
+
+	now := time.Now()
+	query, args, err := sqlx.In(`
+		UPDATE embedding_cache
+		SET last_used_at = ?
+		WHERE model = ? AND text_hash IN (?) AND last_used_at < ?
+	`, now, model, hashes, now.Add(-embeddingTouchInterval))
+	if err != nil {
+		return fmt.Errorf("failed to build embedding cache query: %w", err)
+	}
+
+	_, err = r.db.ExecContext(ctx, r.db.Rebind(query), args...)
+	return err
+}
+
+// PurgeUnused удаляет записи, которые не читались с before: эмбеддинги удалённых датасетов
+// и старых версий текста больше не запрашиваются и уходят по истечении срока
+func (r *EmbeddingCacheMySQLRepository) PurgeUnused(ctx context.Context, before time.Time) (int64, error) {
+	var total int64
+	for {
+		result, err := r.db.ExecContext(ctx, `DELETE FROM embedding_cache WHERE last_used_at < ? LIMIT ?`, before, embeddingCacheBatch)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to purge embedding cache: %w", err))
+			return total, err
+		}
+
+		n, err := result.RowsAffected()
+		if err != nil {
+			return total, err
+		}
+		total += n
+		if n < embeddingCacheBatch {
+			return total, nil
+		}
+	}
+}
+
+func (r *EmbeddingCacheMySQLRepository) PutEmbeddings(ctx context.Context, model string, vectors map[string][]float32) error {
+	hashes := make([]string, 0, len(vectors))
+	for hash := range vectors {
+		hashes = append(hashes, hash)
+	}
+
+	for start := 0; start < len(hashes); start += embeddingCacheBatch {
+		end := min(start+embeddingCacheBatch, len(hashes))
+
+		placeholders := make([]string, 0, end-start)
+		args := make([]any, 0, (end-start)*4)
+		for _, hash := range hashes[start:end] {
+			placeholders = append(placeholders, "(?, ?, ?, ?)")
+			args = append(args, model, hash, len(vectors[hash]), encodeVector(vectors[hash]))
+		}
+
+		query := `INSERT IGNORE INTO embedding_cache (model, text_hash, dims, vector) VALUES ` + strings.Join(placeholders, ", ")
+		if _, err := r.db.ExecContext(ctx, query, args...); err != nil {
+			logger.Error(fmt.Errorf("failed to put cached embeddings: %w", err))
+			return err
+		}
+	}
+
+	return nil
+}
+
+// encodeVector упаковывает вектор как little-endian float32
+func encodeVector(vector []float32) []byte {
+	buf := make([]byte, 4*len(vector))
+	for i, v := range vector {
+		binary.LittleEndian.PutUint32(buf[i*4:], math.Float32bits(v))
+	}
+	return buf
+}
+
+func decodeVector(buf []byte) []float32 {
+	vector := make([]float32, len(buf)/4)
+	for i := range vector {
+		vector[i] = math.Float32frombits(binary.LittleEndian.Uint32(buf[i*4:]))
+	}
+	return vector
+}
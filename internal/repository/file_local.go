@@ -0,0 +1,296 @@
+package repository
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// metaDir — каталог с сайдкарами метаданных. Ключи с сегментами на «.» запрещены,
+// поэтому ни сайдкары, ни временные файлы не пересекаются с объектами
+const metaDir = ".meta"
+
+// FileLocalRepository хранит объекты в каталоге на диске, ключ объекта — относительный путь.
+// Запись атомарна: файл пишется во временный и переименовывается, так что читатель
+// никогда не видит недописанный объект. Content-Type хранится в сайдкаре .meta/<key>.json
+type FileLocalRepository struct {
+	root string
+}
+
+type fileMeta struct {
+	ContentType string `json:"content_type"`
+}
+
+func NewFileLocalRepository(cfg *config.Config) (*FileLocalRepository, error) {
+	root, err := filepath.Abs(cfg.Storage.LocalPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve storage path: %w", err)
+	}
+
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		logger.Error(fmt.Errorf("failed to create storage directory: %w", err))
+		return nil, err
+	}
+
+	logger.Info(fmt.Sprintf("Using local file storage at %s", root))
+
+	return &FileLocalRepository{
+		root: root,
+	}, nil
+}
+
+// objectPath превращает ключ в путь на диске и не даёт выйти за пределы корня
+func (r *FileLocalRepository) objectPath(key string) (string, error) {
+	clean := path.Clean("/" + key)[1:]
+	if clean == "" || clean != key {
+		return "", fmt.Errorf("invalid object key: %q", key)
+	}
+	for _, segment := range strings.Split(clean, "/") {
+		if strings.HasPrefix(segment, ".") {
+			return "", fmt.Errorf("invalid object key: %q", key)
+		}
+	}
+
+	return filepath.Join(r.root, filepath.FromSlash(clean)), nil
+}
+
+func (r *FileLocalRepository) metaPath(key string) string {
+	return filepath.Join(r.root, metaDir, filepath.FromSlash(key)+".json")
+}
+
+func (r *FileLocalRepository) Upload(ctx context.Context, key string, content io.Reader, size int64, contentType string) error {
+	target, err := r.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	meta, err := json.Marshal(fileMeta{ContentType: contentType})
+	if err != nil {
+		return fmt.Errorf("failed to marshal file metadata: %w", err)
+	}
+
+	// сайдкар пишется первым: объект без сайдкара отдаётся как application/octet-stream,
+	// а сайдкар без объекта ни на что не влияет
+	if err := writeFileAtomic(r.metaPath(key), bytes.NewReader(meta), -1); err != nil {
+		logger.Error(fmt.Errorf("failed to write metadata for %s: %w", key, err))
+		return err
+	}
+
+	if err := writeFileAtomic(target, content, size); err != nil {
+		if _, statErr := os.Stat(target); errors.Is(statErr, fs.ErrNotExist) {
+			_ = os.Remove(r.metaPath(key))
+		}
+		logger.Error(fmt.Errorf("failed to write file %s: %w", key, err))
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("file saved to local storage: %s", key))
+	return nil
+}
+
+// writeFileAtomic пишет во временный файл рядом с целевым и переименовывает его.
+// При size >= 0 файл с другим числом байт не сохраняется, как и в MinIO
+func writeFileAtomic(target string, content io.Reader, size int64) error {
+	dir := filepath.Dir(target)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(dir, ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if size >= 0 {
+		// лишний байт сверх size нужен, чтобы заметить слишком длинный поток
+		content = io.LimitReader(content, size+1)
+	}
+	written, err := io.Copy(tmp, content)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+	if size >= 0 && written != size {
+		tmp.Close()
+		return fmt.Errorf("content size mismatch: expected %d bytes, got %d", size, written)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), target)
+}
+
+// Open открывает файл на чтение. ETag строится из времени изменения и размера:
+// запись всегда заменяет файл целиком, поэтому новое содержимое даёт новый ETag
+func (r *FileLocalRepository) Open(ctx context.Context, key string) (io.ReadSeekCloser, *domain.FileInfo, error) {
+	target, err := r.objectPath(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	file, err := os.Open(target)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to open file %s: %w", key, err))
+		return nil, nil, err
+	}
+
+	stat, err := file.Stat()
+	if err != nil {
+		file.Close()
+		logger.Error(fmt.Errorf("failed to stat file %s: %w", key, err))
+		return nil, nil, err
+	}
+
+	logger.Debug(fmt.Sprintf("file opened from local storage: %s", key))
+	return file, &domain.FileInfo{
+		Path:        key,
+		Size:        stat.Size(),
+		ModifiedAt:  stat.ModTime(),
+		ContentType: r.contentType(key),
+		ETag:        fmt.Sprintf(`"%x-%x"`, stat.ModTime().UnixNano(), stat.Size()),
+	}, nil
+}
+
+// contentType читает Content-Type из сайдкара
+func (r *FileLocalRepository) contentType(key string) string {
+	const fallback = "application/octet-stream"
+
+	data, err := os.ReadFile(r.metaPath(key))
+	if err != nil {
+		if !errors.Is(err, fs.ErrNotExist) {
+			logger.Warn(fmt.Sprintf("failed to read metadata for %s: %v", key, err))
+		}
+		return fallback
+	}
+
+	var meta fileMeta
+	if err := json.Unmarshal(data, &meta); err != nil || meta.ContentType == "" {
+		return fallback
+	}
+	return meta.ContentType
+}
+
+// Delete удаляет объект, его сайдкар и опустевшие каталоги. Отсутствующий объект не ошибка
+func (r *FileLocalRepository) Delete(ctx context.Context, key string) error {
+	target, err := r.objectPath(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.Remove(target); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Error(fmt.Errorf("failed to delete file %s: %w", key, err))
+		return err
+	}
+	if err := os.Remove(r.metaPath(key)); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		logger.Warn(fmt.Sprintf("failed to delete metadata for %s: %v", key, err))
+	}
+
+	r.removeEmptyDirs(filepath.Dir(target), r.root)
+	r.removeEmptyDirs(filepath.Dir(r.metaPath(key)), filepath.Join(r.root, metaDir))
+
+	logger.Debug(fmt.Sprintf("file deleted from local storage: %s", key))
+	return nil
+}
+
+// removeEmptyDirs поднимается от dir к stop и удаляет пустые каталоги
+func (r *FileLocalRepository) removeEmptyDirs(dir, stop string) {
+	for dir != stop && strings.HasPrefix(dir, stop) {
+		if err := os.Remove(dir); err != nil {
+			return
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+func (r *FileLocalRepository) Exists(ctx context.Context, key string) (bool, error) {
+	target, err := r.objectPath(key)
+	if err != nil {
+		return false, err
+	}
+
+	_, err = os.Stat(target)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		logger.Error(fmt.Errorf("failed to check file existence: %w", err))
+		return false, err
+	}
+
+	return true, nil
+}
+
+// List возвращает все объекты под prefix, пропуская сайдкары и временные файлы
+func (r *FileLocalRepository) List(ctx context.Context, prefix string) ([]domain.FileInfo, error) {
+	// обход начинается с каталога префикса, а не со всего хранилища
+	start := r.root
+	if dir := path.Dir(prefix); dir != "." && !strings.HasPrefix(dir, ".") {
+		start = filepath.Join(r.root, filepath.FromSlash(dir))
+	}
+
+	files := make([]domain.FileInfo, 0)
+	err := filepath.WalkDir(start, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if p == start && errors.Is(err, fs.ErrNotExist) {
+				return filepath.SkipAll
+			}
+			return err
+		}
+		if p == r.root {
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if d.IsDir() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(r.root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, domain.FileInfo{
+			Path:       key,
+			Size:       info.Size(),
+			ModifiedAt: info.ModTime(),
+		})
+		return nil
+	})
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to list local storage: %w", err))
+		return nil, err
+	}
+
+	return files, nil
+}
@@ -1,12 +1,13 @@
 package repository
 
 import (
-	"bytes"
 	"context"
 	"fmt"
 	"io"
+	"strings"
 
 	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
 	"github.com/anton1ks96/college-core-api/pkg/logger"
 	"github.com/minio/minio-go/v7"
 	"github.com/minio/minio-go/v7/pkg/credentials"
@@ -17,7 +18,7 @@ type FileMinIORepository struct {
 	bucket string
 }
 
-func NewFileRepository(cfg *config.Config) (*FileMinIORepository, error) {
+func NewFileMinIORepository(cfg *config.Config) (*FileMinIORepository, error) {
 	client, err := minio.New(cfg.MinIO.Endpoint, &minio.Options{
 		Creds:  credentials.NewStaticV4(cfg.MinIO.AccessKey, cfg.MinIO.SecretKey, ""),
 		Secure: cfg.MinIO.UseSSL,
@@ -52,15 +53,8 @@ func NewFileRepository(cfg *config.Config) (*FileMinIORepository, error) {
 	}, nil
 }
 
-func (r *FileMinIORepository) Upload(ctx context.Context, path string, content io.Reader, contentType string) error {
-	buf := new(bytes.Buffer)
-	size, err := io.Copy(buf, content)
-	if err != nil {
-		logger.Error(fmt.Errorf("failed to read content: %w", err))
-		return err
-	}
-
-	_, err = r.client.PutObject(ctx, r.bucket, path, buf, size, minio.PutObjectOptions{
+func (r *FileMinIORepository) Upload(ctx context.Context, path string, content io.Reader, size int64, contentType string) error {
+	_, err := r.client.PutObject(ctx, r.bucket, path, content, size, minio.PutObjectOptions{
 		ContentType: contentType,
 	})
 
@@ -73,23 +67,38 @@ func (r *FileMinIORepository) Upload(ctx context.Context, path string, content i
 	return nil
 }
 
-func (r *FileMinIORepository) Download(ctx context.Context, path string) ([]byte, error) {
+// Open возвращает объект MinIO, который читается по мере запроса. Seek переоткрывает
+// поток с нужного смещения, поэтому Range-запросы не скачивают объект целиком
+func (r *FileMinIORepository) Open(ctx context.Context, path string) (io.ReadSeekCloser, *domain.FileInfo, error) {
 	object, err := r.client.GetObject(ctx, r.bucket, path, minio.GetObjectOptions{})
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to get object from MinIO: %w", err))
-		return nil, err
+		return nil, nil, err
 	}
-	defer object.Close()
 
-	buf := new(bytes.Buffer)
-	_, err = io.Copy(buf, object)
+	stat, err := object.Stat()
 	if err != nil {
-		logger.Error(fmt.Errorf("failed to read object content: %w", err))
-		return nil, err
+		object.Close()
+		logger.Error(fmt.Errorf("failed to stat object in MinIO: %w", err))
+		return nil, nil, err
 	}
 
-	logger.Debug(fmt.Sprintf("file downloaded from MinIO: %s", path))
-	return buf.Bytes(), nil
+	logger.Debug(fmt.Sprintf("file opened from MinIO: %s", path))
+	return object, &domain.FileInfo{
+		Path:        path,
+		Size:        stat.Size,
+		ModifiedAt:  stat.LastModified,
+		ContentType: stat.ContentType,
+		ETag:        quoteETag(stat.ETag),
+	}, nil
+}
+
+// quoteETag приводит ETag к виду из RFC 7232: MinIO отдаёт его без кавычек
+func quoteETag(etag string) string {
+	if etag == "" || strings.HasPrefix(etag, `"`) || strings.HasPrefix(etag, `W/"`) {
+		return etag
+	}
+	return `"` + etag + `"`
 }
 
 func (r *FileMinIORepository) Delete(ctx context.Context, path string) error {
@@ -103,6 +112,24 @@ func (r *FileMinIORepository) Delete(ctx context.Context, path string) error {
 	return nil
 }
 
+// List возвращает все объекты под prefix
+func (r *FileMinIORepository) List(ctx context.Context, prefix string) ([]domain.FileInfo, error) {
+	files := make([]domain.FileInfo, 0)
+	for object := range r.client.ListObjects(ctx, r.bucket, minio.ListObjectsOptions{Prefix: prefix, Recursive: true}) {
+		if object.Err != nil {
+			logger.Error(fmt.Errorf("failed to list objects in MinIO: %w", object.Err))
+			return nil, object.Err
+		}
+		files = append(files, domain.FileInfo{
+			Path:       object.Key,
+			Size:       object.Size,
+			ModifiedAt: object.LastModified,
+		})
+	}
+
+	return files, nil
+}
+
 func (r *FileMinIORepository) Exists(ctx context.Context, path string) (bool, error) {
 	_, err := r.client.StatObject(ctx, r.bucket, path, minio.StatObjectOptions{})
 	if err != nil {
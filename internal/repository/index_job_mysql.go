@@ -0,0 +1,232 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type IndexJobMySQLRepository struct {
+	db  *sqlx.DB
+	cfg *config.Config
+}
+
+func NewIndexJobRepository(cfg *config.Config, db *sqlx.DB) *IndexJobMySQLRepository {
+	return &IndexJobMySQLRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+const indexJobColumns = `id, dataset_id, version, status, attempts, max_attempts, chunks_total, chunks_embedded, last_error, run_after, started_at, finished_at, created_at, updated_at`
+
+func (r *IndexJobMySQLRepository) Create(ctx context.Context, job *domain.IndexJob) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID v7: %w", err)
+	}
+
+	now := time.Now()
+	job.ID = id.String()
+	job.Status = domain.IndexJobQueued
+	job.Attempts = 0
+	job.RunAfter = now
+	job.CreatedAt = now
+	job.UpdatedAt = now
+
+	query := `
+		INSERT INTO index_jobs (id, dataset_id, version, status, attempts, max_attempts, run_after, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		job.ID,
+		job.DatasetID,
+		job.Version,
+		job.Status,
+		job.Attempts,
+		job.MaxAttempts,
+		job.RunAfter,
+		job.CreatedAt,
+		job.UpdatedAt,
+	)
+
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to create index job: %w", err))
+		return err
+	}
+
+	logger.Debug(fmt.Sprintf("index job %s queued for dataset %s version %d", job.ID, job.DatasetID, job.Version))
+	return nil
+}
+
+func (r *IndexJobMySQLRepository) GetPendingByDatasetID(ctx context.Context, datasetID string, version int) (*domain.IndexJob, error) {
+	var job domain.IndexJob
+	query := `
+		SELECT ` + indexJobColumns + `
+		FROM index_jobs
+		WHERE dataset_id = ? AND version = ? AND status = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &job, query, datasetID, version, domain.IndexJobQueued)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Error(fmt.Errorf("failed to get pending index job for dataset %s: %w", datasetID, err))
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+func (r *IndexJobMySQLRepository) GetLatestByDatasetID(ctx context.Context, datasetID string) (*domain.IndexJob, error) {
+	var job domain.IndexJob
+	query := `
+		SELECT ` + indexJobColumns + `
+		FROM index_jobs
+		WHERE dataset_id = ?
+		ORDER BY created_at DESC
+		LIMIT 1
+	`
+
+	err := r.db.GetContext(ctx, &job, query, datasetID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Error(fmt.Errorf("failed to get latest index job for dataset %s: %w", datasetID, err))
+		return nil, err
+	}
+
+	return &job, nil
+}
+
+// ClaimNext атомарно забирает ближайшую готовую к запуску задачу и переводит её в running.
+// SKIP LOCKED позволяет нескольким воркерам (и нескольким инстансам) не блокировать друг друга.
+// Задача в running, не обновлявшаяся дольше staleAfter, считается брошенной упавшим процессом
+// и забирается заново. Возвращает nil, nil если задач нет
+func (r *IndexJobMySQLRepository) ClaimNext(ctx context.Context, staleAfter time.Duration) (*domain.IndexJob, error) {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var job domain.IndexJob
+	query := `
+		SELECT ` + indexJobColumns + `
+		FROM index_jobs
+		WHERE (status = ? AND run_after <= ?) OR (status = ? AND updated_at < ?)
+		ORDER BY run_after ASC
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`
+
+	now := time.Now()
+	err = tx.GetContext(ctx, &job, query, domain.IndexJobQueued, now, domain.IndexJobRunning, now.Add(-staleAfter))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		logger.Error(fmt.Errorf("failed to select next index job: %w", err))
+		return nil, err
+	}
+
+	updateQuery := `
+		UPDATE index_jobs
+		SET status = ?, attempts = attempts + 1, chunks_total = 0, chunks_embedded = 0, started_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	if _, err := tx.ExecContext(ctx, updateQuery, domain.IndexJobRunning, now, now, job.ID); err != nil {
+		logger.Error(fmt.Errorf("failed to claim index job %s: %w", job.ID, err))
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	job.Status = domain.IndexJobRunning
+	job.Attempts++
+	job.ChunksTotal = 0
+	job.ChunksEmbedded = 0
+	job.StartedAt = &now
+	job.UpdatedAt = now
+
+	return &job, nil
+}
+
+func (r *IndexJobMySQLRepository) UpdateProgress(ctx context.Context, id string, embedded, total int) error {
+	query := `
+		UPDATE index_jobs
+		SET chunks_embedded = ?, chunks_total = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, embedded, total, time.Now(), id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to update progress of index job %s: %w", id, err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *IndexJobMySQLRepository) MarkSucceeded(ctx context.Context, id string) error {
+	now := time.Now()
+	query := `
+		UPDATE index_jobs
+		SET status = ?, last_error = NULL, finished_at = ?, updated_at = ?
+		WHERE id = ?
+	`
+
+	_, err := r.db.ExecContext(ctx, query, domain.IndexJobSucceeded, now, now, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to mark index job %s as succeeded: %w", id, err))
+		return err
+	}
+
+	return nil
+}
+
+// MarkFailed сохраняет ошибку задачи. Если retryAt задан, задача возвращается в очередь,
+// иначе считается окончательно проваленной
+func (r *IndexJobMySQLRepository) MarkFailed(ctx context.Context, id, lastError string, retryAt *time.Time) error {
+	now := time.Now()
+
+	var err error
+	if retryAt != nil {
+		query := `
+			UPDATE index_jobs
+			SET status = ?, last_error = ?, run_after = ?, updated_at = ?
+			WHERE id = ?
+		`
+		_, err = r.db.ExecContext(ctx, query, domain.IndexJobQueued, lastError, *retryAt, now, id)
+	} else {
+		query := `
+			UPDATE index_jobs
+			SET status = ?, last_error = ?, finished_at = ?, updated_at = ?
+			WHERE id = ?
+		`
+		_, err = r.db.ExecContext(ctx, query, domain.IndexJobFailed, lastError, now, now, id)
+	}
+
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to mark index job %s as failed: %w", id, err))
+		return err
+	}
+
+	return nil
+}
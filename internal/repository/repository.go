@@ -2,8 +2,11 @@ package repository
 
 import (
 	"context"
+	"fmt"
 	"io"
+	"time"
 
+	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/domain"
 )
 
@@ -15,18 +18,58 @@ type DatasetRepository interface {
 	GetAll(ctx context.Context, offset, limit int) ([]domain.Dataset, int, error)
 	Update(ctx context.Context, dataset *domain.Dataset) error
 	Delete(ctx context.Context, id string) error
+	DeleteWithCleanup(ctx context.Context, id string, tasks []domain.CleanupTask) error
+	SoftDelete(ctx context.Context, id, deletedBy string) error
+	Restore(ctx context.Context, id string) error
+	GetDeletedByID(ctx context.Context, id string) (*domain.Dataset, error)
+	GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error)
+	GetDeletedBefore(ctx context.Context, before time.Time, limit int) ([]domain.Dataset, error)
 	UpdateIndexedAt(ctx context.Context, id string) error
+	SetIndexedAt(ctx context.Context, id string, indexedAt *time.Time) error
 	ExistsByUserIDAndTopicID(ctx context.Context, userID, topicID string) (bool, error)
+	GetByTopicID(ctx context.Context, topicID string) ([]domain.Dataset, error)
+	GetIndexedByTopicIDAll(ctx context.Context, topicID string, limit int) ([]domain.Dataset, error)
+	GetIndexedByTopicIDAndUserID(ctx context.Context, topicID, userID string, limit int) ([]domain.Dataset, error)
 	SetTag(ctx context.Context, id string, tag *string) error
 	GetByTagAll(ctx context.Context, tag string, offset, limit int) ([]domain.Dataset, int, error)
 	GetByTagAndTeacherID(ctx context.Context, tag, teacherID string, offset, limit int) ([]domain.Dataset, int, error)
 }
 
+type DatasetVersionRepository interface {
+	Create(ctx context.Context, version *domain.DatasetVersion) error
+	GetByDatasetID(ctx context.Context, datasetID string) ([]domain.DatasetVersion, error)
+	GetAll(ctx context.Context) ([]domain.DatasetVersion, error)
+	GetByVersion(ctx context.Context, datasetID string, version int) (*domain.DatasetVersion, error)
+	CreateNext(ctx context.Context, version *domain.DatasetVersion, store func(version *domain.DatasetVersion) error) error
+	UpdateIndexedAt(ctx context.Context, datasetID string, version int) error
+}
+
+// FileRepository хранит файлы датасетов. Содержимое не буферизуется целиком:
+// Upload читает content потоком (size < 0 — размер неизвестен), Open отдаёт объект
+// с поддержкой Seek для Range-запросов вместе с размером, Content-Type и ETag
 type FileRepository interface {
-	Upload(ctx context.Context, path string, content io.Reader, contentType string) error
-	Download(ctx context.Context, path string) ([]byte, error)
+	Upload(ctx context.Context, path string, content io.Reader, size int64, contentType string) error
+	Open(ctx context.Context, path string) (io.ReadSeekCloser, *domain.FileInfo, error)
 	Delete(ctx context.Context, path string) error
 	Exists(ctx context.Context, path string) (bool, error)
+	List(ctx context.Context, prefix string) ([]domain.FileInfo, error)
+}
+
+const (
+	StorageMinIO = "minio"
+	StorageLocal = "local"
+)
+
+// NewFileRepository создаёт файловое хранилище, выбранное в storage.driver
+func NewFileRepository(cfg *config.Config) (FileRepository, error) {
+	switch cfg.Storage.Driver {
+	case "", StorageMinIO:
+		return NewFileMinIORepository(cfg)
+	case StorageLocal:
+		return NewFileLocalRepository(cfg)
+	default:
+		return nil, fmt.Errorf("unknown storage driver: %s", cfg.Storage.Driver)
+	}
 }
 
 type TopicRepository interface {
@@ -34,6 +77,11 @@ type TopicRepository interface {
 	GetByID(ctx context.Context, id string) (*domain.Topic, error)
 	GetByCreatorID(ctx context.Context, creatorID string, offset, limit int) ([]domain.Topic, int, error)
 	GetAll(ctx context.Context, offset, limit int) ([]domain.Topic, int, error)
+	UpdateChunkingStrategy(ctx context.Context, id, strategy string) error
+	SoftDelete(ctx context.Context, id, deletedBy string) error
+	Restore(ctx context.Context, id string) error
+	GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 	AddAssignments(ctx context.Context, assignments []domain.TopicAssignment) error
 	RemoveAssignment(ctx context.Context, topicID, studentID string) error
 	GetAssignmentsByStudentID(ctx context.Context, studentID string) ([]domain.TopicAssignment, error)
@@ -53,7 +101,18 @@ type DatasetPermissionRepository interface {
 type VectorRepository interface {
 	EnsureCollection(ctx context.Context, vectorSize uint64) error
 	UpsertChunks(ctx context.Context, datasetID string, version int, title string, chunks []domain.ChunkData, vectors [][]float32) (int, error)
-	Search(ctx context.Context, datasetID string, version int, queryVector []float32, k uint64) ([]domain.SearchHit, error)
+	Search(ctx context.Context, targets []domain.SearchTarget, queryVector []float32, k uint64) ([]domain.SearchHit, error)
+	ListChunks(ctx context.Context, datasetID string, version int) ([]domain.ChunkData, error)
+	ListVersions(ctx context.Context) ([]domain.SearchTarget, error)
+	DeleteByDatasetID(ctx context.Context, datasetID string) error
+	DeleteByDatasetVersion(ctx context.Context, datasetID string, version int) error
+	DeleteChunksFrom(ctx context.Context, datasetID string, version, fromChunkID int) error
+}
+
+type AnswerCacheRepository interface {
+	EnsureCollection(ctx context.Context, vectorSize uint64) error
+	Lookup(ctx context.Context, datasetID string, version int, indexedAt time.Time, questionVector []float32, threshold float32, notBefore time.Time) (*domain.CachedAnswer, error)
+	Store(ctx context.Context, answer *domain.CachedAnswer, indexedAt time.Time, questionVector []float32) error
 	DeleteByDatasetID(ctx context.Context, datasetID string) error
 }
 
@@ -63,7 +122,38 @@ type SavedChatRepository interface {
 	GetByDatasetID(ctx context.Context, datasetID string, offset, limit int) ([]domain.SavedChat, int, error)
 	Update(ctx context.Context, chat *domain.SavedChat) error
 	Delete(ctx context.Context, id string) error
+	SoftDelete(ctx context.Context, id, deletedBy string) error
+	Restore(ctx context.Context, id string) error
+	GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error)
+	PurgeDeleted(ctx context.Context, before time.Time) (int64, error)
 	GetMessagesByChatID(ctx context.Context, chatID string) ([]domain.ChatMessage, error)
 	SaveMessages(ctx context.Context, chatID string, messages []domain.ChatMessage) error
 	DeleteMessages(ctx context.Context, chatID string) error
 }
+
+type UsageRepository interface {
+	Create(ctx context.Context, usage *domain.LLMUsage) error
+	SumTokensSince(ctx context.Context, userID string, since time.Time) (int64, error)
+}
+
+type EmbeddingCacheRepository interface {
+	GetEmbeddings(ctx context.Context, model string, hashes []string) (map[string][]float32, error)
+	PutEmbeddings(ctx context.Context, model string, vectors map[string][]float32) error
+	PurgeUnused(ctx context.Context, before time.Time) (int64, error)
+}
+
+type CleanupTaskRepository interface {
+	ClaimNext(ctx context.Context, lease time.Duration) (*domain.CleanupTask, error)
+	Complete(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id, lastError string, retryAt time.Time) error
+}
+
+type IndexJobRepository interface {
+	Create(ctx context.Context, job *domain.IndexJob) error
+	GetPendingByDatasetID(ctx context.Context, datasetID string, version int) (*domain.IndexJob, error)
+	GetLatestByDatasetID(ctx context.Context, datasetID string) (*domain.IndexJob, error)
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (*domain.IndexJob, error)
+	UpdateProgress(ctx context.Context, id string, embedded, total int) error
+	MarkSucceeded(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id, lastError string, retryAt *time.Time) error
+}
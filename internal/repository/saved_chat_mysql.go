@@ -66,7 +66,7 @@ func (r *SavedChatMySQLRepository) GetByID(ctx context.Context, id string) (*dom
 	query := `
 		SELECT id, dataset_id, title, created_by, user_id, created_at, updated_at
 		FROM saved_chats
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &chat, query, id)
@@ -85,7 +85,7 @@ func (r *SavedChatMySQLRepository) GetByDatasetID(ctx context.Context, datasetID
 	var chats []domain.SavedChat
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM saved_chats WHERE dataset_id = ?`
+	countQuery := `SELECT COUNT(*) FROM saved_chats WHERE dataset_id = ? AND deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery, datasetID)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count saved chats for dataset %s: %w", datasetID, err))
@@ -95,7 +95,7 @@ func (r *SavedChatMySQLRepository) GetByDatasetID(ctx context.Context, datasetID
 	query := `
 		SELECT id, dataset_id, title, created_by, user_id, created_at, updated_at
 		FROM saved_chats
-		WHERE dataset_id = ?
+		WHERE dataset_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -115,7 +115,7 @@ func (r *SavedChatMySQLRepository) Update(ctx context.Context, chat *domain.Save
 	query := `
 		UPDATE saved_chats
 		SET title = ?, updated_at = ?
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	result, err := r.db.ExecContext(ctx, query,
@@ -164,6 +164,92 @@ func (r *SavedChatMySQLRepository) Delete(ctx context.Context, id string) error
 	return nil
 }
 
+// SoftDelete переносит сохранённый чат в корзину вместе с сообщениями
+func (r *SavedChatMySQLRepository) SoftDelete(ctx context.Context, id, deletedBy string) error {
+	query := `UPDATE saved_chats SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), deletedBy, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to soft delete saved chat %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("chat not found")
+	}
+
+	logger.Debug(fmt.Sprintf("saved chat %s moved to trash", id))
+	return nil
+}
+
+func (r *SavedChatMySQLRepository) Restore(ctx context.Context, id string) error {
+	query := `UPDATE saved_chats SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to restore saved chat %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("item not found in trash")
+	}
+
+	logger.Debug(fmt.Sprintf("saved chat %s restored from trash", id))
+	return nil
+}
+
+func (r *SavedChatMySQLRepository) GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error) {
+	var items []domain.TrashItem
+	var total int
+
+	countQuery := `SELECT COUNT(*) FROM saved_chats WHERE deleted_at IS NOT NULL`
+	err := r.db.GetContext(ctx, &total, countQuery)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to count deleted saved chats: %w", err))
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, title, created_by AS owner, deleted_at, COALESCE(deleted_by, '') AS deleted_by
+		FROM saved_chats
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	err = r.db.SelectContext(ctx, &items, query, limit, offset)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get deleted saved chats: %w", err))
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// PurgeDeleted окончательно удаляет чаты, пролежавшие в корзине дольше срока хранения
+func (r *SavedChatMySQLRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `DELETE FROM saved_chats WHERE deleted_at IS NOT NULL AND deleted_at < ?`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to purge deleted saved chats: %w", err))
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (r *SavedChatMySQLRepository) GetMessagesByChatID(ctx context.Context, chatID string) ([]domain.ChatMessage, error) {
 	var messages []domain.ChatMessage
 
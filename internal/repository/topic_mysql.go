@@ -37,8 +37,8 @@ func (r *TopicMySQLRepository) Create(ctx context.Context, topic *domain.Topic)
 	topic.UpdatedAt = time.Now()
 
 	query := `
-		INSERT INTO topics (id, title, description, created_by, created_by_id, created_at, updated_at)
-		VALUES (?, ?, ?, ?, ?, ?, ?)
+		INSERT INTO topics (id, title, description, created_by, created_by_id, chunking_strategy, created_at, updated_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
 	`
 
 	_, err = r.db.ExecContext(ctx, query,
@@ -47,6 +47,7 @@ func (r *TopicMySQLRepository) Create(ctx context.Context, topic *domain.Topic)
 		topic.Description,
 		topic.CreatedBy,
 		topic.CreatedByID,
+		topic.ChunkingStrategy,
 		topic.CreatedAt,
 		topic.UpdatedAt,
 	)
@@ -63,9 +64,9 @@ func (r *TopicMySQLRepository) Create(ctx context.Context, topic *domain.Topic)
 func (r *TopicMySQLRepository) GetByID(ctx context.Context, id string) (*domain.Topic, error) {
 	var topic domain.Topic
 	query := `
-		SELECT id, title, description, created_by, created_by_id, created_at, updated_at
+		SELECT id, title, description, created_by, created_by_id, chunking_strategy, created_at, updated_at
 		FROM topics
-		WHERE id = ?
+		WHERE id = ? AND deleted_at IS NULL
 	`
 
 	err := r.db.GetContext(ctx, &topic, query, id)
@@ -84,7 +85,7 @@ func (r *TopicMySQLRepository) GetByCreatorID(ctx context.Context, creatorID str
 	var topics []domain.Topic
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM topics WHERE created_by_id = ?`
+	countQuery := `SELECT COUNT(*) FROM topics WHERE created_by_id = ? AND deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery, creatorID)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count topics for creator %s: %w", creatorID, err))
@@ -92,9 +93,9 @@ func (r *TopicMySQLRepository) GetByCreatorID(ctx context.Context, creatorID str
 	}
 
 	query := `
-		SELECT id, title, description, created_by, created_by, created_at, updated_at
+		SELECT id, title, description, created_by, created_by, chunking_strategy, created_at, updated_at
 		FROM topics
-		WHERE created_by_id = ?
+		WHERE created_by_id = ? AND deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -112,7 +113,7 @@ func (r *TopicMySQLRepository) GetAll(ctx context.Context, offset, limit int) ([
 	var topics []domain.Topic
 	var total int
 
-	countQuery := `SELECT COUNT(*) FROM topics`
+	countQuery := `SELECT COUNT(*) FROM topics WHERE deleted_at IS NULL`
 	err := r.db.GetContext(ctx, &total, countQuery)
 	if err != nil {
 		logger.Error(fmt.Errorf("failed to count all topics: %w", err))
@@ -120,8 +121,9 @@ func (r *TopicMySQLRepository) GetAll(ctx context.Context, offset, limit int) ([
 	}
 
 	query := `
-		SELECT id, title, description, created_by, created_by_id, created_at, updated_at
+		SELECT id, title, description, created_by, created_by_id, chunking_strategy, created_at, updated_at
 		FROM topics
+		WHERE deleted_at IS NULL
 		ORDER BY created_at DESC
 		LIMIT ? OFFSET ?
 	`
@@ -135,6 +137,119 @@ func (r *TopicMySQLRepository) GetAll(ctx context.Context, offset, limit int) ([
 	return topics, total, nil
 }
 
+func (r *TopicMySQLRepository) UpdateChunkingStrategy(ctx context.Context, id, strategy string) error {
+	query := `UPDATE topics SET chunking_strategy = ?, updated_at = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, strategy, time.Now(), id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to update chunking strategy for topic %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("topic not found")
+	}
+
+	return nil
+}
+
+// SoftDelete переносит тему в корзину, датасеты темы остаются доступны
+func (r *TopicMySQLRepository) SoftDelete(ctx context.Context, id, deletedBy string) error {
+	query := `UPDATE topics SET deleted_at = ?, deleted_by = ? WHERE id = ? AND deleted_at IS NULL`
+
+	result, err := r.db.ExecContext(ctx, query, time.Now(), deletedBy, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to soft delete topic %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("topic not found")
+	}
+
+	logger.Debug(fmt.Sprintf("topic %s moved to trash", id))
+	return nil
+}
+
+func (r *TopicMySQLRepository) Restore(ctx context.Context, id string) error {
+	query := `UPDATE topics SET deleted_at = NULL, deleted_by = NULL WHERE id = ? AND deleted_at IS NOT NULL`
+
+	result, err := r.db.ExecContext(ctx, query, id)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to restore topic %s: %w", id, err))
+		return err
+	}
+
+	rowsAffected, err := result.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if rowsAffected == 0 {
+		return fmt.Errorf("item not found in trash")
+	}
+
+	logger.Debug(fmt.Sprintf("topic %s restored from trash", id))
+	return nil
+}
+
+func (r *TopicMySQLRepository) GetDeleted(ctx context.Context, offset, limit int) ([]domain.TrashItem, int, error) {
+	var items []domain.TrashItem
+	var total int
+
+	countQuery := `SELECT COUNT(*) FROM topics WHERE deleted_at IS NOT NULL`
+	err := r.db.GetContext(ctx, &total, countQuery)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to count deleted topics: %w", err))
+		return nil, 0, err
+	}
+
+	query := `
+		SELECT id, title, created_by AS owner, deleted_at, COALESCE(deleted_by, '') AS deleted_by
+		FROM topics
+		WHERE deleted_at IS NOT NULL
+		ORDER BY deleted_at DESC
+		LIMIT ? OFFSET ?
+	`
+
+	err = r.db.SelectContext(ctx, &items, query, limit, offset)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to get deleted topics: %w", err))
+		return nil, 0, err
+	}
+
+	return items, total, nil
+}
+
+// PurgeDeleted окончательно удаляет темы, пролежавшие в корзине дольше срока хранения.
+// Тема, на которую ещё ссылаются датасеты, остаётся в корзине: каскад обнулил бы их
+// topic_id и assignment_id, и датасеты молча потеряли бы тему, стратегию разбиения и доступ преподавателя
+func (r *TopicMySQLRepository) PurgeDeleted(ctx context.Context, before time.Time) (int64, error) {
+	query := `
+		DELETE FROM topics
+		WHERE deleted_at IS NOT NULL AND deleted_at < ?
+			AND NOT EXISTS (SELECT 1 FROM datasets d WHERE d.topic_id = topics.id)
+	`
+
+	result, err := r.db.ExecContext(ctx, query, before)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to purge deleted topics: %w", err))
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (r *TopicMySQLRepository) AddAssignments(ctx context.Context, assignments []domain.TopicAssignment) error {
 	if len(assignments) == 0 {
 		return nil
@@ -179,10 +294,11 @@ func (r *TopicMySQLRepository) GetAssignmentsByStudentID(ctx context.Context, st
 	var assignments []domain.TopicAssignment
 
 	query := `
-		SELECT id, topic_id, student_id, student_name, assigned_by, assigned_by_id, assigned_at
-		FROM topic_assignments
-		WHERE student_id = ?
-		ORDER BY assigned_at DESC
+		SELECT ta.id, ta.topic_id, ta.student_id, ta.student_name, ta.assigned_by, ta.assigned_by_id, ta.assigned_at
+		FROM topic_assignments ta
+		INNER JOIN topics t ON ta.topic_id = t.id AND t.deleted_at IS NULL
+		WHERE ta.student_id = ?
+		ORDER BY ta.assigned_at DESC
 	`
 
 	err := r.db.SelectContext(ctx, &assignments, query, studentID)
@@ -210,8 +326,8 @@ func (r *TopicMySQLRepository) GetAssignmentsWithDetailsByStudentID(ctx context.
 			t.updated_at as topic_updated_at,
 			CASE WHEN d.id IS NOT NULL THEN 1 ELSE 0 END as has_dataset
 		FROM topic_assignments ta
-		INNER JOIN topics t ON ta.topic_id = t.id
-		LEFT JOIN datasets d ON d.user_id = ta.student_id AND d.topic_id = ta.topic_id
+		INNER JOIN topics t ON ta.topic_id = t.id AND t.deleted_at IS NULL
+		LEFT JOIN datasets d ON d.user_id = ta.student_id AND d.topic_id = ta.topic_id AND d.deleted_at IS NULL
 		WHERE ta.student_id = ?
 		ORDER BY ta.assigned_at DESC
 	`
@@ -229,10 +345,11 @@ func (r *TopicMySQLRepository) GetAssignmentsByTopicID(ctx context.Context, topi
 	var assignments []domain.TopicAssignment
 
 	query := `
-		SELECT id, topic_id, student_id, student_name, assigned_by, assigned_by_id, assigned_at
-		FROM topic_assignments
-		WHERE topic_id = ?
-		ORDER BY assigned_at DESC
+		SELECT ta.id, ta.topic_id, ta.student_id, ta.student_name, ta.assigned_by, ta.assigned_by_id, ta.assigned_at
+		FROM topic_assignments ta
+		INNER JOIN topics t ON ta.topic_id = t.id AND t.deleted_at IS NULL
+		WHERE ta.topic_id = ?
+		ORDER BY ta.assigned_at DESC
 	`
 
 	err := r.db.SelectContext(ctx, &assignments, query, topicID)
@@ -248,9 +365,10 @@ func (r *TopicMySQLRepository) GetAssignmentByID(ctx context.Context, id string)
 	var assignment domain.TopicAssignment
 
 	query := `
-		SELECT id, topic_id, student_id, student_name, assigned_by, assigned_by_id, assigned_at
-		FROM topic_assignments
-		WHERE id = ?
+		SELECT ta.id, ta.topic_id, ta.student_id, ta.student_name, ta.assigned_by, ta.assigned_by_id, ta.assigned_at
+		FROM topic_assignments ta
+		INNER JOIN topics t ON ta.topic_id = t.id AND t.deleted_at IS NULL
+		WHERE ta.id = ?
 	`
 
 	err := r.db.GetContext(ctx, &assignment, query, id)
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+)
+
+type UsageMySQLRepository struct {
+	db  *sqlx.DB
+	cfg *config.Config
+}
+
+func NewUsageRepository(cfg *config.Config, db *sqlx.DB) *UsageMySQLRepository {
+	return &UsageMySQLRepository{
+		db:  db,
+		cfg: cfg,
+	}
+}
+
+func (r *UsageMySQLRepository) Create(ctx context.Context, usage *domain.LLMUsage) error {
+	id, err := uuid.NewV7()
+	if err != nil {
+		return fmt.Errorf("failed to generate UUID v7: %w", err)
+	}
+
+	usage.ID = id.String()
+	usage.CreatedAt = time.Now()
+
+	query := `
+		INSERT INTO llm_usage (id, user_id, role, dataset_id, model, prompt_tokens, completion_tokens, total_tokens, estimated, latency_ms, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err = r.db.ExecContext(ctx, query,
+		usage.ID,
+		usage.UserID,
+		usage.Role,
+		usage.DatasetID,
+		usage.Model,
+		usage.PromptTokens,
+		usage.CompletionTokens,
+		usage.TotalTokens,
+		usage.Estimated,
+		usage.LatencyMs,
+		usage.CreatedAt,
+	)
+
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to create llm usage record: %w", err))
+		return err
+	}
+
+	return nil
+}
+
+// SumTokensSince возвращает суммарный расход токенов пользователя начиная с since
+func (r *UsageMySQLRepository) SumTokensSince(ctx context.Context, userID string, since time.Time) (int64, error) {
+	var total int64
+	query := `
+		SELECT COALESCE(SUM(total_tokens), 0)
+		FROM llm_usage
+		WHERE user_id = ? AND created_at >= ?
+	`
+
+	if err := r.db.GetContext(ctx, &total, query, userID, since); err != nil {
+		logger.Error(fmt.Errorf("failed to sum llm usage for user %s: %w", userID, err))
+		return 0, err
+	}
+
+	return total, nil
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"crypto/md5"
 	"fmt"
+	"sort"
 
 	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/domain"
@@ -62,11 +63,15 @@ func (r *VectorQdrantRepository) UpsertChunks(
 			Id:      qdrant.NewIDNum(pid),
 			Vectors: qdrant.NewVectorsDense(vectors[i]),
 			Payload: qdrant.NewValueMap(map[string]any{
-				"dataset_id": datasetID,
-				"version":    version,
-				"chunk_id":   ch.Index,
-				"title":      title,
-				"text":       ch.Text,
+				"dataset_id":    datasetID,
+				"version":       version,
+				"chunk_id":      ch.Index,
+				"title":         title,
+				"section_title": ch.SectionTitle,
+				"text":          ch.Text,
+				"start_offset":  ch.StartOffset,
+				"end_offset":    ch.EndOffset,
+				"content_hash":  ch.ContentHash,
 			}),
 		})
 	}
@@ -82,18 +87,33 @@ func (r *VectorQdrantRepository) UpsertChunks(
 	return len(points), nil
 }
 
+// Search ищет по нескольким датасетам сразу: dataset_id должен входить в список,
+// а версия точки — совпадать с запрошенной версией своего датасета
 func (r *VectorQdrantRepository) Search(
 	ctx context.Context,
-	datasetID string,
-	version int,
+	targets []domain.SearchTarget,
 	queryVector []float32,
 	k uint64,
 ) ([]domain.SearchHit, error) {
+	if len(targets) == 0 {
+		return []domain.SearchHit{}, nil
+	}
+
+	ids := make([]string, len(targets))
+	versions := make([]*qdrant.Condition, len(targets))
+	for i, t := range targets {
+		ids[i] = t.DatasetID
+		versions[i] = qdrant.NewFilterAsCondition(&qdrant.Filter{
+			Must: []*qdrant.Condition{
+				qdrant.NewMatch("dataset_id", t.DatasetID),
+				qdrant.NewMatchInt("version", int64(t.Version)),
+			},
+		})
+	}
+
 	filter := &qdrant.Filter{
-		Must: []*qdrant.Condition{
-			qdrant.NewMatch("dataset_id", datasetID),
-			qdrant.NewMatchInt("version", int64(version)),
-		},
+		Must:   []*qdrant.Condition{qdrant.NewMatchKeywords("dataset_id", ids...)},
+		Should: versions,
 	}
 
 	scored, err := r.client.Query(ctx, &qdrant.QueryPoints{
@@ -125,9 +145,18 @@ func (r *VectorQdrantRepository) Search(
 		if v, ok := sp.Payload["title"]; ok {
 			hit.Title = v.GetStringValue()
 		}
+		if v, ok := sp.Payload["section_title"]; ok {
+			hit.SectionTitle = v.GetStringValue()
+		}
 		if v, ok := sp.Payload["text"]; ok {
 			hit.Text = v.GetStringValue()
 		}
+		if v, ok := sp.Payload["start_offset"]; ok {
+			hit.StartOffset = int(v.GetIntegerValue())
+		}
+		if v, ok := sp.Payload["end_offset"]; ok {
+			hit.EndOffset = int(v.GetIntegerValue())
+		}
 
 		hits = append(hits, hit)
 	}
@@ -135,6 +164,107 @@ func (r *VectorQdrantRepository) Search(
 	return hits, nil
 }
 
+// scrollPageSize — количество точек, запрашиваемых у Qdrant за один scroll
+const scrollPageSize = 256
+
+// ListChunks возвращает все чанки версии датасета, упорядоченные по chunk_id
+func (r *VectorQdrantRepository) ListChunks(ctx context.Context, datasetID string, version int) ([]domain.ChunkData, error) {
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch("dataset_id", datasetID),
+			qdrant.NewMatchInt("version", int64(version)),
+		},
+	}
+
+	chunks := make([]domain.ChunkData, 0)
+	var offset *qdrant.PointId
+	for {
+		points, next, err := r.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: r.collection,
+			Filter:         filter,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPageSize)),
+			WithPayload:    qdrant.NewWithPayload(true),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points for dataset %s version %d: %w", datasetID, version, err)
+		}
+
+		for _, p := range points {
+			var chunk domain.ChunkData
+			if v, ok := p.Payload["chunk_id"]; ok {
+				chunk.Index = int(v.GetIntegerValue())
+			}
+			if v, ok := p.Payload["section_title"]; ok {
+				chunk.SectionTitle = v.GetStringValue()
+			}
+			if v, ok := p.Payload["text"]; ok {
+				chunk.Text = v.GetStringValue()
+			}
+			if v, ok := p.Payload["start_offset"]; ok {
+				chunk.StartOffset = int(v.GetIntegerValue())
+			}
+			if v, ok := p.Payload["end_offset"]; ok {
+				chunk.EndOffset = int(v.GetIntegerValue())
+			}
+			if v, ok := p.Payload["content_hash"]; ok {
+				chunk.ContentHash = v.GetStringValue()
+			}
+			chunks = append(chunks, chunk)
+		}
+
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+
+	sort.Slice(chunks, func(i, j int) bool {
+		return chunks[i].Index < chunks[j].Index
+	})
+
+	return chunks, nil
+}
+
+// ListVersions возвращает все пары датасет+версия, для которых в коллекции есть точки
+func (r *VectorQdrantRepository) ListVersions(ctx context.Context) ([]domain.SearchTarget, error) {
+	seen := make(map[domain.SearchTarget]bool)
+	targets := make([]domain.SearchTarget, 0)
+	var offset *qdrant.PointId
+	for {
+		points, next, err := r.client.ScrollAndOffset(ctx, &qdrant.ScrollPoints{
+			CollectionName: r.collection,
+			Offset:         offset,
+			Limit:          qdrant.PtrOf(uint32(scrollPageSize)),
+			WithPayload:    qdrant.NewWithPayloadInclude("dataset_id", "version"),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to scroll points: %w", err)
+		}
+
+		for _, p := range points {
+			var target domain.SearchTarget
+			if v, ok := p.Payload["dataset_id"]; ok {
+				target.DatasetID = v.GetStringValue()
+			}
+			if v, ok := p.Payload["version"]; ok {
+				target.Version = int(v.GetIntegerValue())
+			}
+			if !seen[target] {
+				seen[target] = true
+				targets = append(targets, target)
+			}
+		}
+
+		if next == nil {
+			break
+		}
+		offset = next
+	}
+
+	return targets, nil
+}
+
 func (r *VectorQdrantRepository) DeleteByDatasetID(ctx context.Context, datasetID string) error {
 	filter := &qdrant.Filter{
 		Must: []*qdrant.Condition{
@@ -153,6 +283,46 @@ func (r *VectorQdrantRepository) DeleteByDatasetID(ctx context.Context, datasetI
 	return nil
 }
 
+func (r *VectorQdrantRepository) DeleteByDatasetVersion(ctx context.Context, datasetID string, version int) error {
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch("dataset_id", datasetID),
+			qdrant.NewMatchInt("version", int64(version)),
+		},
+	}
+
+	_, err := r.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: r.collection,
+		Points:         qdrant.NewPointsSelectorFilter(filter),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete points for dataset %s version %d: %w", datasetID, version, err)
+	}
+
+	return nil
+}
+
+// DeleteChunksFrom удаляет точки версии с chunk_id >= fromChunkID
+func (r *VectorQdrantRepository) DeleteChunksFrom(ctx context.Context, datasetID string, version, fromChunkID int) error {
+	filter := &qdrant.Filter{
+		Must: []*qdrant.Condition{
+			qdrant.NewMatch("dataset_id", datasetID),
+			qdrant.NewMatchInt("version", int64(version)),
+			qdrant.NewRange("chunk_id", &qdrant.Range{Gte: qdrant.PtrOf(float64(fromChunkID))}),
+		},
+	}
+
+	_, err := r.client.Delete(ctx, &qdrant.DeletePoints{
+		CollectionName: r.collection,
+		Points:         qdrant.NewPointsSelectorFilter(filter),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to delete points for dataset %s version %d from chunk %d: %w", datasetID, version, fromChunkID, err)
+	}
+
+	return nil
+}
+
 // pointID генерирует детерминированный uint64 ID точки из dataset_id, version и chunk_id
 // Берёт MD5-хеш строки "dataset_id:version:chunk_id" и использует первые 6 байт (48 бит) как число
 func pointID(datasetID string, version, chunkID int) uint64 {
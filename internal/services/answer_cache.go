@@ -0,0 +1,56 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// replayCachedAnswer отправляет сохранённый ответ на похожий вопрос к той же версии индекса.
+// Возвращает false, если подходящего ответа нет и нужно идти в LLM
+func (s *DatasetServiceImpl) replayCachedAnswer(ctx context.Context, events chan<- domain.AskEvent, target searchTarget, questionVector []float32) bool {
+	cfg := s.cfg.RAG.AnswerCache
+
+	notBefore := time.Time{}
+	if cfg.TTL > 0 {
+		notBefore = time.Now().Add(-cfg.TTL)
+	}
+
+	cached, err := s.repos.AnswerCache.Lookup(ctx, target.dataset.ID, target.version, target.indexedAt, questionVector, float32(cfg.Threshold), notBefore)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("answer cache lookup failed for dataset %s: %v", target.dataset.ID, err))
+		return false
+	}
+	if cached == nil {
+		return false
+	}
+
+	logger.Debug(fmt.Sprintf("answer cache hit for dataset %s version %d (score %.3f)", target.dataset.ID, target.version, cached.Score))
+
+	if !s.sendEvent(ctx, events, domain.AskEvent{Type: "delta", Delta: cached.Answer, Cached: true}) {
+		return true
+	}
+	if !s.sendEvent(ctx, events, domain.AskEvent{Type: "citations", Citations: cached.Citations, Cached: true}) {
+		return true
+	}
+	s.sendEvent(ctx, events, domain.AskEvent{Type: "done", Cached: true})
+
+	return true
+}
+
+func (s *DatasetServiceImpl) storeCachedAnswer(ctx context.Context, target searchTarget, question, answer string, citations []domain.Citation, questionVector []float32) {
+	cached := &domain.CachedAnswer{
+		DatasetID: target.dataset.ID,
+		Version:   target.version,
+		Question:  question,
+		Answer:    answer,
+		Citations: citations,
+	}
+
+	if err := s.repos.AnswerCache.Store(ctx, cached, target.indexedAt, questionVector); err != nil {
+		logger.Warn(fmt.Sprintf("failed to cache answer for dataset %s: %v", target.dataset.ID, err))
+	}
+}
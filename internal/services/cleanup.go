@@ -0,0 +1,137 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// CleanupServiceImpl доводит удаление датасета до MinIO и Qdrant. Задачи лежат в MySQL
+// и повторяются с растущей задержкой, пока каждое хранилище не подтвердит удаление
+type CleanupServiceImpl struct {
+	repos *Repositories
+	cfg   *config.Config
+	wake  chan struct{}
+	wg    sync.WaitGroup
+}
+
+func NewCleanupService(repos *Repositories, cfg *config.Config) *CleanupServiceImpl {
+	return &CleanupServiceImpl{
+		repos: repos,
+		cfg:   cfg,
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Notify будит воркер сразу после постановки новых задач
+func (s *CleanupServiceImpl) Notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *CleanupServiceImpl) Start(ctx context.Context) {
+	s.wg.Add(1)
+	go s.worker(ctx)
+
+	logger.Info("Cleanup worker started")
+}
+
+func (s *CleanupServiceImpl) Wait() {
+	s.wg.Wait()
+}
+
+func (s *CleanupServiceImpl) worker(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Cleanup.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// processNext выполняет одну задачу очистки. Возвращает false если готовых задач нет
+func (s *CleanupServiceImpl) processNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// задача скрыта от других воркеров на время выполнения с запасом
+	task, err := s.repos.CleanupTask.ClaimNext(ctx, 2*s.cfg.Cleanup.TaskTimeout)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to claim cleanup task: %w", err))
+		return false
+	}
+	if task == nil {
+		return false
+	}
+
+	taskCtx, cancel := context.WithTimeout(ctx, s.cfg.Cleanup.TaskTimeout)
+	err = s.run(taskCtx, task)
+	cancel()
+
+	s.finish(task, err)
+	return true
+}
+
+func (s *CleanupServiceImpl) run(ctx context.Context, task *domain.CleanupTask) error {
+	switch task.Kind {
+	case domain.CleanupFile:
+		return s.repos.File.Delete(ctx, task.Target)
+	case domain.CleanupVectors:
+		return s.repos.Vector.DeleteByDatasetID(ctx, task.DatasetID)
+	case domain.CleanupAnswerCache:
+		return s.repos.AnswerCache.DeleteByDatasetID(ctx, task.DatasetID)
+	default:
+		return fmt.Errorf("unknown cleanup task kind: %s", task.Kind)
+	}
+}
+
+func (s *CleanupServiceImpl) finish(task *domain.CleanupTask, taskErr error) {
+	// контекст воркера может быть уже отменён при остановке, а результат сохранить нужно
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if taskErr == nil {
+		if err := s.repos.CleanupTask.Complete(ctx, task.ID); err != nil {
+			logger.Error(fmt.Errorf("failed to complete cleanup task %s: %w", task.ID, err))
+		}
+		logger.Debug(fmt.Sprintf("cleanup task %s (%s) for dataset %s done", task.ID, task.Kind, task.DatasetID))
+		return
+	}
+
+	retryAt := time.Now().Add(s.backoff(task.Attempts))
+	if err := s.repos.CleanupTask.MarkFailed(ctx, task.ID, taskErr.Error(), retryAt); err != nil {
+		logger.Error(fmt.Errorf("failed to finish cleanup task %s: %w", task.ID, err))
+	}
+
+	logger.Warn(fmt.Sprintf("cleanup task %s (%s) for dataset %s failed (attempt %d), retry at %s: %v",
+		task.ID, task.Kind, task.DatasetID, task.Attempts, retryAt.Format(time.DateTime), taskErr))
+}
+
+func (s *CleanupServiceImpl) backoff(attempt int) time.Duration {
+	delay := s.cfg.Cleanup.RetryBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= s.cfg.Cleanup.MaxBackoff {
+			return s.cfg.Cleanup.MaxBackoff
+		}
+	}
+	return delay
+}
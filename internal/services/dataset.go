@@ -3,36 +3,42 @@ package services
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
-	"sort"
+	"path"
 	"strings"
+	"time"
 
+	"github.com/anton1ks96/college-core-api/internal/client/embedding"
 	"github.com/anton1ks96/college-core-api/internal/client/llm"
 	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/domain"
 	"github.com/anton1ks96/college-core-api/internal/rag"
+	"github.com/anton1ks96/college-core-api/internal/repository"
 	"github.com/anton1ks96/college-core-api/pkg/logger"
 	"github.com/google/uuid"
 )
 
-const datasetVersion = 1
-
 type DatasetServiceImpl struct {
 	repos   *Repositories
 	clients *Clients
 	cfg     *config.Config
+	index   IndexService
+	lexical *lexicalIndexCache
 }
 
-func NewDatasetService(repos *Repositories, clients *Clients, cfg *config.Config) *DatasetServiceImpl {
+func NewDatasetService(repos *Repositories, clients *Clients, cfg *config.Config, index IndexService) *DatasetServiceImpl {
 	return &DatasetServiceImpl{
 		repos:   repos,
 		clients: clients,
 		cfg:     cfg,
+		index:   index,
+		lexical: newLexicalIndexCache(),
 	}
 }
 
-func (s *DatasetServiceImpl) Create(ctx context.Context, userID, username, title, assignmentID string, content io.Reader) (*domain.Dataset, error) {
+func (s *DatasetServiceImpl) Create(ctx context.Context, userID, username, title, assignmentID string, content io.Reader, size int64) (*domain.Dataset, error) {
 	assignment, err := s.repos.Topic.GetAssignmentByID(ctx, assignmentID)
 	if err != nil {
 		return nil, fmt.Errorf("assignment not found")
@@ -51,12 +57,6 @@ func (s *DatasetServiceImpl) Create(ctx context.Context, userID, username, title
 		return nil, fmt.Errorf("dataset already exists for this topic")
 	}
 
-	buf := new(bytes.Buffer)
-	size, err := io.Copy(buf, content)
-	if err != nil {
-		return nil, fmt.Errorf("failed to read content: %w", err)
-	}
-
 	if size > s.cfg.Limits.MaxFileSize {
 		return nil, fmt.Errorf("file size exceeds limit: %d > %d bytes", size, s.cfg.Limits.MaxFileSize)
 	}
@@ -66,17 +66,21 @@ func (s *DatasetServiceImpl) Create(ctx context.Context, userID, username, title
 		return nil, fmt.Errorf("failed to generate UUID v7: %w", err)
 	}
 
+	const firstVersion = 1
+
 	dataset := &domain.Dataset{
-		ID:           id.String(),
-		UserID:       userID,
-		Author:       username,
-		Title:        title,
-		FilePath:     fmt.Sprintf("students/%s/%s/dataset.md", userID, uuid.New().String()),
-		TopicID:      &assignment.TopicID,
-		AssignmentID: &assignmentID,
+		ID:             id.String(),
+		UserID:         userID,
+		Author:         username,
+		Title:          title,
+		FilePath:       versionFilePath(fmt.Sprintf("students/%s/%s", userID, uuid.New().String()), firstVersion),
+		TopicID:        &assignment.TopicID,
+		AssignmentID:   &assignmentID,
+		CurrentVersion: firstVersion,
 	}
 
-	err = s.repos.File.Upload(ctx, dataset.FilePath, bytes.NewReader(buf.Bytes()), "text/markdown")
+	// файл передаётся в хранилище потоком, размер известен из multipart-заголовка
+	err = s.repos.File.Upload(ctx, dataset.FilePath, content, size, "text/markdown")
 	if err != nil {
 		return nil, fmt.Errorf("failed to upload file: %w", err)
 	}
@@ -87,6 +91,23 @@ func (s *DatasetServiceImpl) Create(ctx context.Context, userID, username, title
 		return nil, fmt.Errorf("failed to save dataset metadata: %w", err)
 	}
 
+	err = s.repos.DatasetVersion.Create(ctx, &domain.DatasetVersion{
+		DatasetID: dataset.ID,
+		Version:   firstVersion,
+		FilePath:  dataset.FilePath,
+		Size:      size,
+		CreatedBy: username,
+	})
+	if err != nil {
+		_ = s.repos.Dataset.Delete(ctx, dataset.ID)
+		_ = s.repos.File.Delete(ctx, dataset.FilePath)
+		return nil, fmt.Errorf("failed to save dataset version: %w", err)
+	}
+
+	if _, err := s.index.Enqueue(ctx, dataset.ID, firstVersion); err != nil {
+		logger.Error(fmt.Errorf("failed to queue indexing for dataset %s: %w", dataset.ID, err))
+	}
+
 	return dataset, nil
 }
 
@@ -121,26 +142,50 @@ func (s *DatasetServiceImpl) GetByID(ctx context.Context, datasetID, userID stri
 		return nil, fmt.Errorf("access denied")
 	}
 
-	content, err := s.repos.File.Download(ctx, dataset.FilePath)
+	content, err := readFile(ctx, s.repos.File, dataset.FilePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to download file: %w", err)
 	}
 
 	response := &domain.DatasetResponse{
-		ID:        dataset.ID,
-		Title:     dataset.Title,
-		Content:   string(content),
-		Author:    dataset.Author,
-		UserID:    dataset.UserID,
-		CreatedAt: dataset.CreatedAt,
-		UpdatedAt: dataset.UpdatedAt,
-		IndexedAt: dataset.IndexedAt,
-		Tag:       dataset.Tag,
+		ID:             dataset.ID,
+		Title:          dataset.Title,
+		Content:        string(content),
+		Author:         dataset.Author,
+		UserID:         dataset.UserID,
+		CreatedAt:      dataset.CreatedAt,
+		UpdatedAt:      dataset.UpdatedAt,
+		IndexedAt:      dataset.IndexedAt,
+		Tag:            dataset.Tag,
+		CurrentVersion: dataset.CurrentVersion,
 	}
 
 	return response, nil
 }
 
+// OpenFile открывает исходный файл текущей версии датасета для потоковой отдачи
+func (s *DatasetServiceImpl) OpenFile(ctx context.Context, datasetID, userID, role string) (io.ReadSeekCloser, *domain.FileInfo, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
+	if err != nil {
+		return nil, nil, err
+	}
+	if !hasAccess {
+		return nil, nil, fmt.Errorf("access denied")
+	}
+
+	file, info, err := s.repos.File.Open(ctx, dataset.FilePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open file: %w", err)
+	}
+
+	return file, info, nil
+}
+
 func (s *DatasetServiceImpl) GetList(ctx context.Context, userID string, role string, page, limit int) (*domain.DatasetListResponse, error) {
 	if page < 1 {
 		page = 1
@@ -174,7 +219,7 @@ func (s *DatasetServiceImpl) GetList(ctx context.Context, userID string, role st
 	}, nil
 }
 
-func (s *DatasetServiceImpl) Update(ctx context.Context, datasetID, userID, title string, content *string) (*domain.Dataset, error) {
+func (s *DatasetServiceImpl) Update(ctx context.Context, datasetID, userID, username, title string, content *string) (*domain.Dataset, error) {
 	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
 	if err != nil {
 		return nil, err
@@ -188,19 +233,348 @@ func (s *DatasetServiceImpl) Update(ctx context.Context, datasetID, userID, titl
 		dataset.Title = title
 	}
 
-	if content != nil && *content != "" {
-		if err := s.repos.File.Upload(ctx, dataset.FilePath, strings.NewReader(*content), "text/markdown"); err != nil {
-			return nil, fmt.Errorf("failed to upload new content: %w", err)
+	// каждое изменение содержимого создаёт новую неизменяемую версию со своим объектом в хранилище
+	contentChanged := content != nil && *content != ""
+	if contentChanged {
+		size := int64(len(*content))
+		if size > s.cfg.Limits.MaxFileSize {
+			return nil, fmt.Errorf("file size exceeds limit: %d > %d bytes", size, s.cfg.Limits.MaxFileSize)
+		}
+
+		version := &domain.DatasetVersion{
+			DatasetID: datasetID,
+			Size:      size,
+			CreatedBy: username,
+		}
+
+		// номер версии выделяется под блокировкой датасета, поэтому путь объекта принадлежит
+		// только этой версии. Если строку сохранить не удалось, объект не удаляется здесь:
+		// без строки он станет сиротой и его уберёт сверка хранилищ
+		dir := path.Dir(dataset.FilePath)
+		err := s.repos.DatasetVersion.CreateNext(ctx, version, func(v *domain.DatasetVersion) error {
+			v.FilePath = versionFilePath(dir, v.Version)
+			if err := s.repos.File.Upload(ctx, v.FilePath, strings.NewReader(*content), size, "text/markdown"); err != nil {
+				return fmt.Errorf("failed to upload new content: %w", err)
+			}
+			return nil
+		})
+		if err != nil {
+			if err.Error() == "dataset not found" {
+				return nil, err
+			}
+			return nil, fmt.Errorf("failed to save dataset version: %w", err)
+		}
+
+		dataset.CurrentVersion = version.Version
+		dataset.FilePath = version.FilePath
+	}
+
+	if err := s.repos.Dataset.Update(ctx, dataset); err != nil {
+		return nil, fmt.Errorf("failed to update dataset: %w", err)
+	}
+
+	if contentChanged {
+		// новая версия ещё не проиндексирована
+		if err := s.repos.Dataset.SetIndexedAt(ctx, datasetID, nil); err != nil {
+			return nil, fmt.Errorf("failed to reset indexed_at: %w", err)
+		}
+		dataset.IndexedAt = nil
+
+		if _, err := s.index.Enqueue(ctx, dataset.ID, dataset.CurrentVersion); err != nil {
+			logger.Error(fmt.Errorf("failed to queue indexing for dataset %s: %w", dataset.ID, err))
 		}
 	}
 
+	return dataset, nil
+}
+
+func (s *DatasetServiceImpl) GetVersions(ctx context.Context, datasetID, userID, role string) (*domain.DatasetVersionListResponse, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	versions, err := s.repos.DatasetVersion.GetByDatasetID(ctx, datasetID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get versions: %w", err)
+	}
+
+	for i := range versions {
+		versions[i].IsCurrent = versions[i].Version == dataset.CurrentVersion
+	}
+
+	return &domain.DatasetVersionListResponse{
+		DatasetID:      datasetID,
+		CurrentVersion: dataset.CurrentVersion,
+		Versions:       versions,
+	}, nil
+}
+
+func (s *DatasetServiceImpl) GetVersion(ctx context.Context, datasetID, userID, role string, version int) (*domain.DatasetVersionResponse, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	v, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	content, err := readFile(ctx, s.repos.File, v.FilePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return &domain.DatasetVersionResponse{
+		DatasetID: datasetID,
+		Version:   v.Version,
+		Content:   string(content),
+		Size:      v.Size,
+		IsCurrent: v.Version == dataset.CurrentVersion,
+		CreatedBy: v.CreatedBy,
+		CreatedAt: v.CreatedAt,
+		IndexedAt: v.IndexedAt,
+	}, nil
+}
+
+// SetCurrentVersion выбирает версию, по которой работает RAG.
+// Если версия ещё не проиндексирована, для неё ставится задача индексации
+func (s *DatasetServiceImpl) SetCurrentVersion(ctx context.Context, datasetID, userID string, version int) (*domain.Dataset, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !checkEditPermission(userID, dataset.UserID) {
+		return nil, fmt.Errorf("access denied: only owner can change current version")
+	}
+
+	v, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	dataset.CurrentVersion = v.Version
+	dataset.FilePath = v.FilePath
+
 	if err := s.repos.Dataset.Update(ctx, dataset); err != nil {
 		return nil, fmt.Errorf("failed to update dataset: %w", err)
 	}
 
+	if err := s.repos.Dataset.SetIndexedAt(ctx, datasetID, v.IndexedAt); err != nil {
+		return nil, fmt.Errorf("failed to update indexed_at: %w", err)
+	}
+	dataset.IndexedAt = v.IndexedAt
+
+	if v.IndexedAt == nil {
+		if _, err := s.index.Enqueue(ctx, datasetID, v.Version); err != nil {
+			logger.Error(fmt.Errorf("failed to queue indexing for dataset %s: %w", datasetID, err))
+		}
+	}
+
 	return dataset, nil
 }
 
+// GetChunks показывает, что реально лежит в индексе для версии датасета (по умолчанию текущей)
+func (s *DatasetServiceImpl) GetChunks(ctx context.Context, datasetID, userID, role string, version int) (*domain.DatasetChunksResponse, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if version == 0 {
+		version = dataset.CurrentVersion
+	} else if _, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, version); err != nil {
+		return nil, err
+	}
+
+	chunks, err := s.repos.Vector.ListChunks(ctx, datasetID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list chunks: %w", err)
+	}
+
+	infos := make([]domain.ChunkInfo, len(chunks))
+	docs := make([]rag.Document, len(chunks))
+	for i, chunk := range chunks {
+		infos[i] = domain.ChunkInfo{
+			ChunkID:      chunk.Index,
+			SectionTitle: chunk.SectionTitle,
+			Size:         len(chunk.Text),
+			Tokens:       rag.EstimateTokens(chunk.Text),
+			Text:         chunk.Text,
+			StartOffset:  chunk.StartOffset,
+			EndOffset:    chunk.EndOffset,
+		}
+		docs[i] = rag.Document{
+			PageContent: chunk.Text,
+			Metadata: rag.ChunkMetadata{
+				ChunkID:      chunk.Index,
+				SectionTitle: chunk.SectionTitle,
+			},
+		}
+	}
+
+	stats := rag.GetChunkStatistics(docs)
+
+	return &domain.DatasetChunksResponse{
+		DatasetID: datasetID,
+		Version:   version,
+		Chunks:    infos,
+		Statistics: domain.ChunkStatistics{
+			TotalChunks: stats.TotalChunks,
+			AvgSize:     stats.AvgSize,
+			MinSize:     stats.MinSize,
+			MaxSize:     stats.MaxSize,
+			Sections:    stats.Sections,
+		},
+	}, nil
+}
+
+// diffContextLines — количество строк контекста вокруг изменений в unified diff
+const diffContextLines = 3
+
+// GetDiff сравнивает две версии датасета. Если to не задан, берётся текущая версия,
+// если не задан from — предыдущая перед to
+func (s *DatasetServiceImpl) GetDiff(ctx context.Context, datasetID, userID, role string, from, to int) (*domain.DatasetDiffResponse, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
+	}
+
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
+	if err != nil {
+		return nil, err
+	}
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
+	}
+
+	if to == 0 {
+		to = dataset.CurrentVersion
+	}
+	if from == 0 {
+		from = to - 1
+	}
+	if from < 1 {
+		return nil, fmt.Errorf("no previous version to compare with")
+	}
+
+	oldContent, err := s.downloadVersion(ctx, datasetID, from)
+	if err != nil {
+		return nil, err
+	}
+	newContent, err := s.downloadVersion(ctx, datasetID, to)
+	if err != nil {
+		return nil, err
+	}
+
+	unified, added, removed := rag.UnifiedDiff(
+		fmt.Sprintf("v%d", from),
+		fmt.Sprintf("v%d", to),
+		oldContent,
+		newContent,
+		diffContextLines,
+	)
+
+	changes := rag.DiffSections(oldContent, newContent)
+	sections := make([]domain.SectionDiff, len(changes))
+	for i, change := range changes {
+		sections[i] = domain.SectionDiff{
+			Title:        change.Title,
+			Status:       change.Status,
+			LinesAdded:   change.LinesAdded,
+			LinesRemoved: change.LinesRemoved,
+		}
+	}
+
+	return &domain.DatasetDiffResponse{
+		DatasetID:    datasetID,
+		FromVersion:  from,
+		ToVersion:    to,
+		LinesAdded:   added,
+		LinesRemoved: removed,
+		Unified:      unified,
+		Sections:     sections,
+	}, nil
+}
+
+func (s *DatasetServiceImpl) GetDiffMarkdown(ctx context.Context, datasetID, userID, role string, from, to int) ([]byte, error) {
+	diff, err := s.GetDiff(ctx, datasetID, userID, role, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return generateDiffMarkdown(diff), nil
+}
+
+func (s *DatasetServiceImpl) downloadVersion(ctx context.Context, datasetID string, version int) (string, error) {
+	v, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, version)
+	if err != nil {
+		return "", err
+	}
+
+	content, err := readFile(ctx, s.repos.File, v.FilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to download file: %w", err)
+	}
+
+	return string(content), nil
+}
+
+func generateDiffMarkdown(diff *domain.DatasetDiffResponse) []byte {
+	var buf bytes.Buffer
+
+	buf.WriteString(fmt.Sprintf("# Changes v%d → v%d\n\n", diff.FromVersion, diff.ToVersion))
+	buf.WriteString(fmt.Sprintf("**Lines added:** %d\n", diff.LinesAdded))
+	buf.WriteString(fmt.Sprintf("**Lines removed:** %d\n\n", diff.LinesRemoved))
+
+	buf.WriteString("## Sections\n\n")
+	if len(diff.Sections) == 0 {
+		buf.WriteString("No section changes\n\n")
+	} else {
+		for _, section := range diff.Sections {
+			buf.WriteString(fmt.Sprintf("- **%s** — %s (+%d −%d)\n", section.Title, section.Status, section.LinesAdded, section.LinesRemoved))
+		}
+		buf.WriteString("\n")
+	}
+
+	buf.WriteString("## Diff\n\n")
+	if diff.Unified == "" {
+		buf.WriteString("Versions are identical\n")
+		return buf.Bytes()
+	}
+	buf.WriteString("```diff\n")
+	buf.WriteString(diff.Unified)
+	buf.WriteString("```\n")
+
+	return buf.Bytes()
+}
+
 func (s *DatasetServiceImpl) Delete(ctx context.Context, datasetID, userID, role string) error {
 	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
 	if err != nil {
@@ -215,16 +589,17 @@ func (s *DatasetServiceImpl) Delete(ctx context.Context, datasetID, userID, role
 		return fmt.Errorf("access denied")
 	}
 
-	err = s.repos.Dataset.Delete(ctx, datasetID)
+	// датасет попадает в корзину, окончательно его удаляет задача очистки корзины
+	err = s.repos.Dataset.SoftDelete(ctx, datasetID, userID)
 	if err != nil {
 		return fmt.Errorf("failed to delete dataset: %w", err)
 	}
 
-	logger.Info(fmt.Sprintf("dataset %s deleted by user %s (role: %s)", datasetID, userID, role))
+	logger.Info(fmt.Sprintf("dataset %s moved to trash by user %s (role: %s)", datasetID, userID, role))
 	return nil
 }
 
-func (s *DatasetServiceImpl) AskQuestion(ctx context.Context, datasetID, userID, role, question string) (<-chan domain.AskEvent, error) {
+func (s *DatasetServiceImpl) AskQuestion(ctx context.Context, datasetID, userID, role string, req domain.AskRequest) (<-chan domain.AskEvent, error) {
 	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
 	if err != nil {
 		return nil, err
@@ -238,22 +613,174 @@ func (s *DatasetServiceImpl) AskQuestion(ctx context.Context, datasetID, userID,
 		return nil, fmt.Errorf("access denied")
 	}
 
-	if dataset.IndexedAt == nil {
+	version := dataset.CurrentVersion
+	indexedAt := dataset.IndexedAt
+	if req.Version != nil && *req.Version != dataset.CurrentVersion {
+		v, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, *req.Version)
+		if err != nil {
+			return nil, err
+		}
+		version = v.Version
+		indexedAt = v.IndexedAt
+	}
+
+	if indexedAt == nil {
 		return nil, fmt.Errorf("dataset is not indexed yet, please wait")
 	}
 
+	history, err := s.loadHistory(ctx, datasetID, role, req)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkQuota(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	targets := []searchTarget{{dataset: dataset, version: version, indexedAt: *indexedAt}}
+	return s.streamAnswer(ctx, newUsageMeter(userID, role, &dataset.ID), targets, req.Question, history), nil
+}
+
+// maxAskDatasets ограничивает число датасетов в одном запросе по тегу или теме.
+// Если под тег или тему попадает больше, запрос отклоняется, а не отвечает по части набора
+const maxAskDatasets = 50
+
+// AskMultiple отвечает на вопрос по объединению датасетов: явному списку, тегу или теме.
+// Явно перечисленные датасеты без доступа дают ошибку, найденные по тегу или теме — пропускаются
+func (s *DatasetServiceImpl) AskMultiple(ctx context.Context, userID, role string, req domain.MultiAskRequest) (<-chan domain.AskEvent, error) {
+	var datasets []domain.Dataset
+	explicit := len(req.DatasetIDs) > 0
+
+	switch {
+	case explicit:
+		seen := make(map[string]bool, len(req.DatasetIDs))
+		for _, id := range req.DatasetIDs {
+			if seen[id] {
+				continue
+			}
+			seen[id] = true
+
+			dataset, err := s.repos.Dataset.GetByID(ctx, id)
+			if err != nil {
+				return nil, err
+			}
+			datasets = append(datasets, *dataset)
+		}
+	case req.Tag != nil && strings.TrimSpace(*req.Tag) != "":
+		tag := strings.ToLower(strings.TrimSpace(*req.Tag))
+
+		var (
+			total int
+			err   error
+		)
+		if role == "admin" {
+			datasets, total, err = s.repos.Dataset.GetByTagAll(ctx, tag, 0, maxAskDatasets)
+		} else {
+			datasets, total, err = s.repos.Dataset.GetByTagAndTeacherID(ctx, tag, userID, 0, maxAskDatasets)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get datasets by tag: %w", err)
+		}
+		if total > maxAskDatasets {
+			return nil, fmt.Errorf("too many datasets")
+		}
+	case req.TopicID != nil && *req.TopicID != "":
+		// доступ и индексация проверяются в запросе, иначе лимит съели бы чужие и непроиндексированные датасеты
+		// лишняя строка показывает, что под тему попадает больше датасетов, чем допускает лимит
+		var err error
+		if role == "admin" {
+			datasets, err = s.repos.Dataset.GetIndexedByTopicIDAll(ctx, *req.TopicID, maxAskDatasets+1)
+		} else {
+			datasets, err = s.repos.Dataset.GetIndexedByTopicIDAndUserID(ctx, *req.TopicID, userID, maxAskDatasets+1)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to get datasets by topic: %w", err)
+		}
+		if len(datasets) > maxAskDatasets {
+			return nil, fmt.Errorf("too many datasets")
+		}
+	default:
+		return nil, fmt.Errorf("dataset_ids, tag or topic_id is required")
+	}
+
+	targets := make([]searchTarget, 0, len(datasets))
+	for i := range datasets {
+		dataset := &datasets[i]
+
+		hasAccess, err := s.hasReadAccess(ctx, dataset.ID, userID, dataset.UserID, role)
+		if err != nil {
+			return nil, err
+		}
+		if !hasAccess {
+			if explicit {
+				return nil, fmt.Errorf("access denied")
+			}
+			continue
+		}
+
+		if dataset.IndexedAt == nil {
+			continue
+		}
+
+		targets = append(targets, searchTarget{dataset: dataset, version: dataset.CurrentVersion, indexedAt: *dataset.IndexedAt})
+	}
+
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no indexed datasets available")
+	}
+
+	history := make([]rag.HistoryTurn, 0, len(req.History))
+	for _, turn := range req.History {
+		history = append(history, rag.HistoryTurn{Question: turn.Question, Answer: turn.Answer})
+	}
+
+	if err := s.checkQuota(ctx, userID, role); err != nil {
+		return nil, err
+	}
+
+	// расход по нескольким датасетам не привязывается ни к одному из них
+	var usageDatasetID *string
+	if len(targets) == 1 {
+		usageDatasetID = &targets[0].dataset.ID
+	}
+
+	return s.streamAnswer(ctx, newUsageMeter(userID, role, usageDatasetID), targets, req.Question, s.boundHistory(history)), nil
+}
+
+// streamAnswer выполняет RAG-пайплайн: condense → embed → hybrid search → rerank → LLM stream.
+// Расход токенов всех вызовов LLM записывается в meter и сохраняется по завершении
+func (s *DatasetServiceImpl) streamAnswer(ctx context.Context, meter *usageMeter, targets []searchTarget, question string, history []rag.HistoryTurn) <-chan domain.AskEvent {
 	events := make(chan domain.AskEvent)
 
+	datasets := make(map[string]*domain.Dataset, len(targets))
+	for _, t := range targets {
+		datasets[t.dataset.ID] = t.dataset
+	}
+	multi := len(targets) > 1
+
 	go func() {
 		defer close(events)
+		defer s.recordUsage(ctx, meter)
 
-		queryVector, err := s.clients.TEI.Embed(ctx, question)
+		searchQuery := s.condenseQuestion(ctx, meter, history, question)
+
+		queryVector, err := s.clients.Embedder.Embed(ctx, searchQuery)
 		if err != nil {
+			if errors.Is(err, embedding.ErrEmbeddingUnavailable) {
+				s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "embedding service unavailable"})
+				return
+			}
 			s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "failed to embed question"})
 			return
 		}
 
-		hits, err := s.repos.Vector.Search(ctx, datasetID, datasetVersion, queryVector, uint64(s.cfg.RAG.SearchTopK))
+		// ответ на уточняющий вопрос зависит от истории, поэтому кэшируются только самостоятельные вопросы
+		cacheable := s.cfg.RAG.AnswerCache.Enabled && len(targets) == 1 && len(history) == 0
+		if cacheable && s.replayCachedAnswer(ctx, events, targets[0], queryVector) {
+			return
+		}
+
+		hits, err := s.hybridSearch(ctx, targets, searchQuery, queryVector)
 		if err != nil {
 			s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "failed to search vectors"})
 			return
@@ -264,21 +791,16 @@ func (s *DatasetServiceImpl) AskQuestion(ctx context.Context, datasetID, userID,
 			return
 		}
 
-		texts := make([]string, len(hits))
-		for i, h := range hits {
-			texts[i] = h.Text
-		}
-
-		reranked, err := s.clients.TEI.Rerank(ctx, question, texts)
+		reranked, err := s.rerank(ctx, searchQuery, hits)
 		if err != nil {
+			if errors.Is(err, embedding.ErrRerankerUnavailable) {
+				s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "reranker service unavailable"})
+				return
+			}
 			s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "failed to rerank"})
 			return
 		}
 
-		sort.Slice(reranked, func(i, j int) bool {
-			return reranked[i].Score > reranked[j].Score
-		})
-
 		topN := s.cfg.RAG.RerankTopN
 		if len(reranked) < topN {
 			topN = len(reranked)
@@ -288,47 +810,121 @@ func (s *DatasetServiceImpl) AskQuestion(ctx context.Context, datasetID, userID,
 		citations := make([]domain.Citation, topN)
 		for i := 0; i < topN; i++ {
 			idx := reranked[i].Index
-			contextChunks[i] = hits[idx].Text
+			hit := hits[idx]
+			dataset := datasets[hit.DatasetID]
+
+			contextChunks[i] = hit.Text
+			if multi {
+				// при поиске по нескольким датасетам модель должна видеть, чей это фрагмент
+				source := dataset.Title
+				if dataset.Author != "" {
+					source += ", автор: " + dataset.Author
+				}
+				contextChunks[i] = fmt.Sprintf("[%s]\n%s", source, hit.Text)
+			}
+
 			citations[i] = domain.Citation{
-				ChunkID:          hits[idx].ChunkID,
+				Marker:           i + 1,
+				ChunkID:          hit.ChunkID,
 				Score:            reranked[i].Score,
-				OriginalScore:    float64(hits[idx].Score),
-				ScoreImprovement: reranked[i].Score - float64(hits[idx].Score),
+				OriginalScore:    float64(hit.Score),
+				ScoreImprovement: reranked[i].Score - float64(hit.Score),
+				DatasetID:        dataset.ID,
+				DatasetTitle:     dataset.Title,
+				Author:           dataset.Author,
+				SectionTitle:     hit.SectionTitle,
+				Text:             hit.Text,
+				StartOffset:      hit.StartOffset,
+				EndOffset:        hit.EndOffset,
 			}
 		}
 
-		messages := []llm.Message{
-			{Role: "system", Content: rag.SystemPrompt},
-			{Role: "user", Content: rag.BuildUserPrompt(question, contextChunks)},
-		}
+		messages := []llm.Message{{Role: "system", Content: rag.SystemPrompt}}
+		messages = append(messages, historyMessages(history)...)
+		messages = append(messages, llm.Message{Role: "user", Content: rag.BuildUserPrompt(question, contextChunks)})
 
 		chunks, errc := s.clients.LLM.ChatCompletionStream(ctx, messages, s.cfg.RAG.LLMTemperature, s.cfg.RAG.LLMMaxTokens)
+		markers := rag.NewMarkerFilter(topN)
+		var answer, reasoning strings.Builder
+		var usage *llm.Usage
+		var model string
+		disconnected := false
 
+	stream:
 		for chunk := range chunks {
+			if chunk.Usage != nil {
+				usage = chunk.Usage
+			}
+			if chunk.Model != "" {
+				model = chunk.Model
+			}
 			for _, choice := range chunk.Choices {
 				if choice.Delta.ReasoningContent != "" {
+					reasoning.WriteString(choice.Delta.ReasoningContent)
 					if !s.sendEvent(ctx, events, domain.AskEvent{Type: "thinking", Delta: choice.Delta.ReasoningContent}) {
-						return
+						disconnected = true
+						break stream
 					}
 				}
-				if choice.Delta.Content != "" {
-					if !s.sendEvent(ctx, events, domain.AskEvent{Type: "delta", Delta: choice.Delta.Content}) {
-						return
+				if delta := markers.Write(choice.Delta.Content); delta != "" {
+					answer.WriteString(delta)
+					if !s.sendEvent(ctx, events, domain.AskEvent{Type: "delta", Delta: delta}) {
+						disconnected = true
+						break stream
 					}
 				}
 			}
 		}
 
-		if err := <-errc; err != nil {
+		err = <-errc
+		meter.add(model, usage, messages, reasoning.String()+answer.String())
+		if disconnected {
+			return
+		}
+
+		if err != nil {
 			s.sendEvent(ctx, events, domain.AskEvent{Type: "error", Error: "llm generation failed"})
 			return
 		}
 
-		s.sendEvent(ctx, events, domain.AskEvent{Type: "citations", Citations: citations})
+		if rest := markers.Flush(); rest != "" {
+			answer.WriteString(rest)
+			if !s.sendEvent(ctx, events, domain.AskEvent{Type: "delta", Delta: rest}) {
+				return
+			}
+		}
+
+		for _, n := range markers.Cited() {
+			citations[n-1].Cited = true
+		}
+
+		if !s.sendEvent(ctx, events, domain.AskEvent{Type: "citations", Citations: citations}) {
+			return
+		}
+
+		if cacheable && answer.Len() > 0 {
+			s.storeCachedAnswer(ctx, targets[0], question, answer.String(), citations, queryVector)
+		}
+
+		// проверка опоры оценивает утверждения реранкером, без него она пропускается
+		if s.cfg.RAG.GroundingEnabled && s.clients.Reranker != nil {
+			contexts := make([]string, topN)
+			for i := range contexts {
+				contexts[i] = citations[i].Text
+			}
+
+			report, err := s.verifyGrounding(ctx, answer.String(), contexts)
+			if err != nil {
+				logger.Warn(fmt.Sprintf("grounding verification failed: %v", err))
+			} else if !s.sendEvent(ctx, events, domain.AskEvent{Type: "grounding", Grounding: report}) {
+				return
+			}
+		}
+
 		s.sendEvent(ctx, events, domain.AskEvent{Type: "done"})
 	}()
 
-	return events, nil
+	return events
 }
 
 // sendEvent отправляет событие в канал с проверкой отмены контекста
@@ -342,7 +938,7 @@ func (s *DatasetServiceImpl) sendEvent(ctx context.Context, ch chan<- domain.Ask
 	}
 }
 
-func (s *DatasetServiceImpl) Reindex(ctx context.Context, datasetID, userID string) (*domain.IndexResponse, error) {
+func (s *DatasetServiceImpl) Reindex(ctx context.Context, datasetID, userID string) (*domain.IndexJob, error) {
 	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
 	if err != nil {
 		return nil, err
@@ -352,61 +948,99 @@ func (s *DatasetServiceImpl) Reindex(ctx context.Context, datasetID, userID stri
 		return nil, fmt.Errorf("access denied: only owner can reindex dataset")
 	}
 
-	content, err := s.repos.File.Download(ctx, dataset.FilePath)
+	job, err := s.index.Enqueue(ctx, datasetID, dataset.CurrentVersion)
 	if err != nil {
-		return nil, fmt.Errorf("failed to download dataset: %w", err)
+		return nil, fmt.Errorf("failed to queue indexing: %w", err)
 	}
 
-	normalized := rag.NormalizeMarkdown(string(content))
-
-	assignmentID := ""
-	if dataset.AssignmentID != nil {
-		assignmentID = *dataset.AssignmentID
-	}
+	logger.Info(fmt.Sprintf("dataset %s queued for reindexing by user %s (job %s)", datasetID, userID, job.ID))
 
-	docs := rag.ChunkStudentMarkdown(normalized, dataset.UserID, assignmentID, datasetVersion, dataset.Title)
-	if len(docs) == 0 {
-		return nil, fmt.Errorf("dataset content is empty or has no sections")
-	}
+	return job, nil
+}
 
-	texts := make([]string, len(docs))
-	for i, doc := range docs {
-		texts[i] = doc.PageContent
+func (s *DatasetServiceImpl) GetIndexStatus(ctx context.Context, datasetID, userID, role string) (*domain.IndexStatusResponse, error) {
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		return nil, err
 	}
 
-	vectors, err := s.clients.TEI.EmbedBatch(ctx, texts)
+	hasAccess, err := s.hasReadAccess(ctx, datasetID, userID, dataset.UserID, role)
 	if err != nil {
-		return nil, fmt.Errorf("failed to generate embeddings: %w", err)
+		return nil, err
 	}
-
-	if err := s.repos.Vector.DeleteByDatasetID(ctx, datasetID); err != nil {
-		return nil, fmt.Errorf("failed to delete old vectors: %w", err)
+	if !hasAccess {
+		return nil, fmt.Errorf("access denied")
 	}
 
-	chunks := make([]domain.ChunkData, len(docs))
-	for i, doc := range docs {
-		chunks[i] = domain.ChunkData{
-			Index: doc.Metadata.ChunkID,
-			Text:  doc.PageContent,
-		}
-	}
+	return s.index.GetStatus(ctx, dataset)
+}
 
-	count, err := s.repos.Vector.UpsertChunks(ctx, datasetID, datasetVersion, dataset.Title, chunks, vectors)
+// WatchIndexStatus опрашивает статус индексации и отправляет его в канал при каждом изменении.
+// Канал закрывается, когда задача завершилась (успешно или окончательно с ошибкой)
+func (s *DatasetServiceImpl) WatchIndexStatus(ctx context.Context, datasetID, userID, role string) (<-chan domain.IndexStatusResponse, error) {
+	status, err := s.GetIndexStatus(ctx, datasetID, userID, role)
 	if err != nil {
-		return nil, fmt.Errorf("failed to upsert vectors: %w", err)
+		return nil, err
 	}
 
-	if err := s.repos.Dataset.UpdateIndexedAt(ctx, datasetID); err != nil {
-		return nil, fmt.Errorf("failed to update indexed_at: %w", err)
-	}
+	updates := make(chan domain.IndexStatusResponse)
+
+	go func() {
+		defer close(updates)
+
+		ticker := time.NewTicker(s.cfg.Indexer.StatusPollInterval)
+		defer ticker.Stop()
+
+		var last *domain.IndexStatusResponse
+		for {
+			if last == nil || statusChanged(last, status) {
+				select {
+				case updates <- *status:
+				case <-ctx.Done():
+					return
+				}
+				last = status
+			}
 
-	logger.Info(fmt.Sprintf("dataset %s reindexed: %d chunks", datasetID, count))
+			if isTerminalIndexStatus(status.Status) {
+				return
+			}
 
-	return &domain.IndexResponse{
-		Success: true,
-		Chunks:  count,
-		Message: fmt.Sprintf("Successfully indexed %d chunks", count),
-	}, nil
+			select {
+			case <-ticker.C:
+			case <-ctx.Done():
+				return
+			}
+
+			dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+			if err != nil {
+				logger.Error(fmt.Errorf("failed to refresh index status of dataset %s: %w", datasetID, err))
+				return
+			}
+
+			status, err = s.index.GetStatus(ctx, dataset)
+			if err != nil {
+				logger.Error(fmt.Errorf("failed to refresh index status of dataset %s: %w", datasetID, err))
+				return
+			}
+		}
+	}()
+
+	return updates, nil
+}
+
+func statusChanged(prev, next *domain.IndexStatusResponse) bool {
+	return prev.JobID != next.JobID ||
+		prev.Status != next.Status ||
+		prev.ChunksEmbedded != next.ChunksEmbedded ||
+		prev.ChunksTotal != next.ChunksTotal ||
+		prev.Attempts != next.Attempts
+}
+
+func isTerminalIndexStatus(status string) bool {
+	return status == domain.IndexJobSucceeded ||
+		status == domain.IndexJobFailed ||
+		status == domain.IndexStatusNotIndexed
 }
 
 func (s *DatasetServiceImpl) SetTag(ctx context.Context, datasetID, userID, role string, tag *string) error {
@@ -468,3 +1102,23 @@ func (s *DatasetServiceImpl) SearchByTag(ctx context.Context, userID, role, tag
 		Limit:    limit,
 	}, nil
 }
+
+// readFile читает объект целиком для сервисов, которым нужен весь текст: ответа API и индексации
+func readFile(ctx context.Context, files repository.FileRepository, filePath string) ([]byte, error) {
+	file, info, err := files.Open(ctx, filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	buf := bytes.NewBuffer(make([]byte, 0, info.Size))
+	if _, err := io.Copy(buf, file); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// versionFilePath строит ключ объекта версии внутри каталога датасета
+func versionFilePath(dir string, version int) string {
+	return fmt.Sprintf("%s/v%d.md", dir, version)
+}
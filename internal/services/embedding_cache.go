@@ -0,0 +1,73 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// embeddingEvictInterval — как часто из кэша эмбеддингов удаляются неиспользуемые записи
+const embeddingEvictInterval = time.Hour
+
+// EmbeddingCacheServiceImpl вытесняет эмбеддинги, которые не читались дольше EmbeddingCacheTTL
+type EmbeddingCacheServiceImpl struct {
+	repos *Repositories
+	cfg   *config.Config
+	wg    sync.WaitGroup
+}
+
+func NewEmbeddingCacheService(repos *Repositories, cfg *config.Config) *EmbeddingCacheServiceImpl {
+	return &EmbeddingCacheServiceImpl{
+		repos: repos,
+		cfg:   cfg,
+	}
+}
+
+func (s *EmbeddingCacheServiceImpl) Start(ctx context.Context) {
+	if s.repos.EmbeddingCache == nil || s.cfg.TEI.EmbeddingCacheTTL <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go s.evictor(ctx)
+
+	logger.Info("Embedding cache evictor started")
+}
+
+func (s *EmbeddingCacheServiceImpl) Wait() {
+	s.wg.Wait()
+}
+
+func (s *EmbeddingCacheServiceImpl) evictor(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(embeddingEvictInterval)
+	defer ticker.Stop()
+
+	for {
+		s.evict(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (s *EmbeddingCacheServiceImpl) evict(ctx context.Context) {
+	n, err := s.repos.EmbeddingCache.PurgeUnused(ctx, time.Now().Add(-s.cfg.TEI.EmbeddingCacheTTL))
+	if err != nil {
+		if ctx.Err() == nil {
+			logger.Error(fmt.Errorf("failed to evict cached embeddings: %w", err))
+		}
+		return
+	}
+	if n > 0 {
+		logger.Info(fmt.Sprintf("evicted %d unused cached embeddings", n))
+	}
+}
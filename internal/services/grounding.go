@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
+)
+
+// maxGroundingClaims ограничивает число запросов к реранкеру на один ответ
+const maxGroundingClaims = 30
+
+// groundingConcurrency — сколько утверждений оценивается реранкером одновременно
+const groundingConcurrency = 4
+
+// verifyGrounding оценивает каждое утверждение ответа реранкером относительно найденных фрагментов.
+// Реранкер здесь служит заменой NLI-модели: высокая релевантность фрагмента считается опорой
+func (s *DatasetServiceImpl) verifyGrounding(ctx context.Context, answer string, contexts []string) (*domain.GroundingReport, error) {
+	claims := rag.SplitClaims(answer)
+	if len(claims) > maxGroundingClaims {
+		claims = claims[:maxGroundingClaims]
+	}
+
+	report := &domain.GroundingReport{
+		Threshold: s.cfg.RAG.GroundingThreshold,
+		Claims:    make([]domain.GroundingClaim, 0, len(claims)),
+	}
+	if len(claims) == 0 || len(contexts) == 0 {
+		return report, nil
+	}
+
+	items, err := s.scoreClaims(ctx, claims, contexts)
+	if err != nil {
+		return nil, err
+	}
+
+	supported := 0
+	for _, item := range items {
+		item.Supported = item.Score >= s.cfg.RAG.GroundingThreshold
+		if item.Supported {
+			supported++
+		}
+
+		report.Claims = append(report.Claims, item)
+	}
+
+	report.SupportedRatio = float64(supported) / float64(len(report.Claims))
+	if supported < len(report.Claims) {
+		report.Warning = fmt.Sprintf("%d of %d claims are not supported by the retrieved context", len(report.Claims)-supported, len(report.Claims))
+	}
+
+	return report, nil
+}
+
+// scoreClaims находит для каждого утверждения лучший фрагмент. Реранкер принимает один запрос
+// за вызов, поэтому утверждения оцениваются параллельно, не больше groundingConcurrency одновременно.
+// Первая ошибка отменяет остальные вызовы
+func (s *DatasetServiceImpl) scoreClaims(ctx context.Context, claims, contexts []string) ([]domain.GroundingClaim, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	items := make([]domain.GroundingClaim, len(claims))
+	sem := make(chan struct{}, groundingConcurrency)
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		firstErr error
+	)
+
+	for i, claim := range claims {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+
+		wg.Add(1)
+		go func(i int, claim string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			results, err := s.clients.Reranker.Rerank(ctx, claim, contexts)
+			if err != nil {
+				errOnce.Do(func() {
+					firstErr = fmt.Errorf("failed to score claim: %w", err)
+					cancel()
+				})
+				return
+			}
+
+			item := domain.GroundingClaim{Text: claim}
+			for _, res := range results {
+				if item.Marker == 0 || res.Score > item.Score {
+					item.Score = res.Score
+					item.Marker = res.Index + 1
+				}
+			}
+			items[i] = item
+		}(i, claim)
+	}
+
+	wg.Wait()
+
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	return items, nil
+}
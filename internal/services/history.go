@@ -0,0 +1,121 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/anton1ks96/college-core-api/internal/client/llm"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+const (
+	// condenseMaxTokens ограничивает длину переформулированного вопроса
+	condenseMaxTokens = 256
+	// condenseTemperature держит переформулировку близкой к детерминированной
+	condenseTemperature = 0.1
+)
+
+// loadHistory собирает историю диалога: сообщения сохранённого чата, затем переданные в запросе ходы.
+// Возвращается только последнее окно, ограниченное по числу ходов и оценке токенов
+func (s *DatasetServiceImpl) loadHistory(ctx context.Context, datasetID, role string, req domain.AskRequest) ([]rag.HistoryTurn, error) {
+	turns := make([]rag.HistoryTurn, 0, len(req.History))
+
+	if req.ChatID != nil && *req.ChatID != "" {
+		if role != "teacher" && role != "admin" {
+			return nil, fmt.Errorf("access denied")
+		}
+
+		chat, err := s.repos.SavedChat.GetByID(ctx, *req.ChatID)
+		if err != nil {
+			return nil, err
+		}
+		if chat.DatasetID != datasetID {
+			return nil, fmt.Errorf("chat does not belong to this dataset")
+		}
+
+		messages, err := s.repos.SavedChat.GetMessagesByChatID(ctx, chat.ID)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get chat messages: %w", err)
+		}
+		for _, msg := range messages {
+			turns = append(turns, rag.HistoryTurn{Question: msg.Question, Answer: msg.Answer})
+		}
+	}
+
+	for _, turn := range req.History {
+		turns = append(turns, rag.HistoryTurn{Question: turn.Question, Answer: turn.Answer})
+	}
+
+	return s.boundHistory(turns), nil
+}
+
+func (s *DatasetServiceImpl) boundHistory(turns []rag.HistoryTurn) []rag.HistoryTurn {
+	maxTurns := s.cfg.RAG.HistoryMaxTurns
+	if maxTurns <= 0 {
+		return nil
+	}
+	if len(turns) > maxTurns {
+		turns = turns[len(turns)-maxTurns:]
+	}
+
+	if s.cfg.RAG.HistoryMaxTokens <= 0 {
+		return turns
+	}
+
+	// самые старые ходы отбрасываются первыми, последний ход остаётся всегда
+	tokens := 0
+	start := len(turns)
+	for start > 0 {
+		turn := turns[start-1]
+		cost := rag.EstimateTokens(turn.Question) + rag.EstimateTokens(turn.Answer)
+		if tokens+cost > s.cfg.RAG.HistoryMaxTokens && start < len(turns) {
+			break
+		}
+		tokens += cost
+		start--
+	}
+
+	return turns[start:]
+}
+
+// condenseQuestion превращает уточняющий вопрос в самостоятельный поисковый запрос.
+// При ошибке LLM поиск выполняется по исходному вопросу
+func (s *DatasetServiceImpl) condenseQuestion(ctx context.Context, meter *usageMeter, history []rag.HistoryTurn, question string) string {
+	if len(history) == 0 {
+		return question
+	}
+
+	messages := []llm.Message{
+		{Role: "system", Content: rag.CondensePrompt},
+		{Role: "user", Content: rag.BuildCondensePrompt(history, question)},
+	}
+
+	resp, err := s.clients.LLM.ChatCompletion(ctx, messages, condenseTemperature, condenseMaxTokens)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("failed to condense question, using original: %v", err))
+		return question
+	}
+	meter.add(resp.Model, resp.Usage, messages, resp.Content())
+
+	condensed := strings.TrimSpace(resp.Content())
+	if condensed == "" {
+		return question
+	}
+
+	return condensed
+}
+
+// historyMessages превращает окно истории в сообщения user/assistant для LLM
+func historyMessages(history []rag.HistoryTurn) []llm.Message {
+	messages := make([]llm.Message, 0, len(history)*2)
+	for _, turn := range history {
+		messages = append(messages,
+			llm.Message{Role: "user", Content: turn.Question},
+			llm.Message{Role: "assistant", Content: turn.Answer},
+		)
+	}
+	return messages
+}
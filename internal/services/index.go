@@ -0,0 +1,399 @@
+package services
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+type IndexServiceImpl struct {
+	repos   *Repositories
+	clients *Clients
+	cfg     *config.Config
+	wake    chan struct{}
+	wg      sync.WaitGroup
+}
+
+func NewIndexService(repos *Repositories, clients *Clients, cfg *config.Config) *IndexServiceImpl {
+	return &IndexServiceImpl{
+		repos:   repos,
+		clients: clients,
+		cfg:     cfg,
+		wake:    make(chan struct{}, 1),
+	}
+}
+
+// permanentIndexError помечает ошибки, повтор которых бессмыслен (датасет удалён, пустой файл)
+type permanentIndexError struct {
+	err error
+}
+
+func (e *permanentIndexError) Error() string { return e.err.Error() }
+func (e *permanentIndexError) Unwrap() error { return e.err }
+
+func (s *IndexServiceImpl) Enqueue(ctx context.Context, datasetID string, version int) (*domain.IndexJob, error) {
+	pending, err := s.repos.IndexJob.GetPendingByDatasetID(ctx, datasetID, version)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check pending index jobs: %w", err)
+	}
+	if pending != nil {
+		return pending, nil
+	}
+
+	job := &domain.IndexJob{
+		DatasetID:   datasetID,
+		Version:     version,
+		MaxAttempts: s.cfg.Indexer.MaxAttempts,
+	}
+
+	if err := s.repos.IndexJob.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create index job: %w", err)
+	}
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+
+	return job, nil
+}
+
+func (s *IndexServiceImpl) Start(ctx context.Context) {
+	workers := max(s.cfg.Indexer.Workers, 1)
+	for i := 0; i < workers; i++ {
+		s.wg.Add(1)
+		go s.worker(ctx)
+	}
+
+	logger.Info(fmt.Sprintf("Index workers started: %d", workers))
+}
+
+func (s *IndexServiceImpl) Wait() {
+	s.wg.Wait()
+}
+
+func (s *IndexServiceImpl) worker(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Indexer.PollInterval)
+	defer ticker.Stop()
+
+	for {
+		for s.processNext(ctx) {
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		case <-s.wake:
+		}
+	}
+}
+
+// processNext выполняет одну задачу из очереди. Возвращает false если очередь пуста
+func (s *IndexServiceImpl) processNext(ctx context.Context) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	// задачи, прерванные остановкой или падением любого инстанса, подхватываются по истечении
+	// двух JobTimeout без обновлений: живая задача к этому времени уже отменена по таймауту
+	job, err := s.repos.IndexJob.ClaimNext(ctx, 2*s.cfg.Indexer.JobTimeout)
+	if err != nil {
+		logger.Error(fmt.Errorf("failed to claim index job: %w", err))
+		return false
+	}
+	if job == nil {
+		return false
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, s.cfg.Indexer.JobTimeout)
+	count, err := s.indexDataset(jobCtx, job)
+	cancel()
+
+	s.finish(job, count, err)
+	return true
+}
+
+func (s *IndexServiceImpl) finish(job *domain.IndexJob, count int, jobErr error) {
+	// контекст воркера может быть уже отменён при остановке, а статус задачи сохранить нужно
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	if jobErr == nil {
+		if err := s.repos.IndexJob.MarkSucceeded(ctx, job.ID); err != nil {
+			logger.Error(fmt.Errorf("failed to finish index job %s: %w", job.ID, err))
+		}
+		logger.Info(fmt.Sprintf("index job %s for dataset %s version %d succeeded: %d chunks", job.ID, job.DatasetID, job.Version, count))
+		return
+	}
+
+	var permanent *permanentIndexError
+	var retryAt *time.Time
+	if !errors.As(jobErr, &permanent) && job.Attempts < job.MaxAttempts {
+		t := time.Now().Add(s.backoff(job.Attempts))
+		retryAt = &t
+	}
+
+	if err := s.repos.IndexJob.MarkFailed(ctx, job.ID, jobErr.Error(), retryAt); err != nil {
+		logger.Error(fmt.Errorf("failed to finish index job %s: %w", job.ID, err))
+	}
+
+	if retryAt != nil {
+		logger.Warn(fmt.Sprintf("index job %s for dataset %s failed (attempt %d/%d), retry at %s: %v",
+			job.ID, job.DatasetID, job.Attempts, job.MaxAttempts, retryAt.Format(time.DateTime), jobErr))
+		return
+	}
+
+	logger.Error(fmt.Errorf("index job %s for dataset %s failed permanently: %w", job.ID, job.DatasetID, jobErr))
+}
+
+func (s *IndexServiceImpl) backoff(attempt int) time.Duration {
+	delay := s.cfg.Indexer.RetryBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= s.cfg.Indexer.MaxBackoff {
+			return s.cfg.Indexer.MaxBackoff
+		}
+	}
+	return delay
+}
+
+// indexEmbedBatchSize — сколько чанков эмбеддится между обновлениями прогресса задачи
+const indexEmbedBatchSize = 32
+
+// indexDataset выполняет полный цикл индексации: normalize → chunk → embed → upsert → indexed_at
+func (s *IndexServiceImpl) indexDataset(ctx context.Context, job *domain.IndexJob) (int, error) {
+	datasetID := job.DatasetID
+
+	dataset, err := s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			return 0, &permanentIndexError{err: err}
+		}
+		return 0, err
+	}
+
+	version, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, job.Version)
+	if err != nil {
+		if err.Error() == "version not found" {
+			return 0, &permanentIndexError{err: err}
+		}
+		return 0, err
+	}
+
+	content, err := readFile(ctx, s.repos.File, version.FilePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to download dataset: %w", err)
+	}
+
+	normalized := rag.NormalizeMarkdown(string(content))
+
+	assignmentID := ""
+	if dataset.AssignmentID != nil {
+		assignmentID = *dataset.AssignmentID
+	}
+
+	chunker, err := s.chunkerFor(ctx, dataset)
+	if err != nil {
+		return 0, err
+	}
+
+	docs := chunker.Chunk(normalized, dataset.UserID, assignmentID, job.Version, dataset.Title)
+	if len(docs) == 0 {
+		return 0, &permanentIndexError{err: fmt.Errorf("dataset content is empty")}
+	}
+
+	chunks := make([]domain.ChunkData, len(docs))
+	for i, doc := range docs {
+		chunks[i] = domain.ChunkData{
+			Index:        doc.Metadata.ChunkID,
+			SectionTitle: doc.Metadata.SectionTitle,
+			Text:         doc.PageContent,
+			StartOffset:  doc.Metadata.StartOffset,
+			EndOffset:    doc.Metadata.EndOffset,
+			ContentHash:  contentHash(doc.PageContent),
+		}
+	}
+
+	// при переиндексации той же версии перезаписываются только изменившиеся точки
+	existing, err := s.repos.Vector.ListChunks(ctx, datasetID, job.Version)
+	if err != nil {
+		return 0, fmt.Errorf("failed to list existing vectors: %w", err)
+	}
+	stored := make(map[int]domain.ChunkData, len(existing))
+	for _, chunk := range existing {
+		stored[chunk.Index] = chunk
+	}
+
+	changed := make([]domain.ChunkData, 0, len(chunks))
+	for _, chunk := range chunks {
+		if old, ok := stored[chunk.Index]; !ok || old != chunk {
+			changed = append(changed, chunk)
+		}
+	}
+
+	s.reportProgress(ctx, job, 0, len(changed))
+
+	vectors := make([][]float32, 0, len(changed))
+	for start := 0; start < len(changed); start += indexEmbedBatchSize {
+		end := min(start+indexEmbedBatchSize, len(changed))
+
+		texts := make([]string, 0, end-start)
+		for _, chunk := range changed[start:end] {
+			texts = append(texts, chunk.Text)
+		}
+
+		batch, err := s.clients.Embedder.EmbedBatch(ctx, texts)
+		if err != nil {
+			return 0, fmt.Errorf("failed to generate embeddings: %w", err)
+		}
+		vectors = append(vectors, batch...)
+
+		s.reportProgress(ctx, job, len(vectors), len(changed))
+	}
+
+	if len(changed) > 0 {
+		if _, err := s.repos.Vector.UpsertChunks(ctx, datasetID, job.Version, dataset.Title, changed, vectors); err != nil {
+			return 0, fmt.Errorf("failed to upsert vectors: %w", err)
+		}
+	}
+
+	// чанков могло стать меньше: лишние точки с прошлой индексации удаляются
+	if len(existing) > len(chunks) {
+		if err := s.repos.Vector.DeleteChunksFrom(ctx, datasetID, job.Version, len(chunks)); err != nil {
+			return 0, fmt.Errorf("failed to delete stale vectors: %w", err)
+		}
+	}
+
+	logger.Debug(fmt.Sprintf("dataset %s version %d: %d of %d chunks changed", datasetID, job.Version, len(changed), len(chunks)))
+
+	if err := s.repos.DatasetVersion.UpdateIndexedAt(ctx, datasetID, job.Version); err != nil {
+		return 0, fmt.Errorf("failed to update version indexed_at: %w", err)
+	}
+
+	// ответы по старому индексу больше не годятся; промах здесь не страшен,
+	// кэш всё равно фильтруется по indexed_at
+	if s.cfg.RAG.AnswerCache.Enabled {
+		if err := s.repos.AnswerCache.DeleteByDatasetID(ctx, datasetID); err != nil {
+			logger.Warn(fmt.Sprintf("failed to invalidate answer cache for dataset %s: %v", datasetID, err))
+		}
+	}
+
+	// текущая версия могла смениться за время индексации, поэтому перечитываем датасет
+	dataset, err = s.repos.Dataset.GetByID(ctx, datasetID)
+	if err != nil {
+		if err.Error() == "dataset not found" {
+			s.dropOrphanVectors(ctx, datasetID, job.Version)
+			return 0, &permanentIndexError{err: err}
+		}
+		return 0, err
+	}
+
+	if dataset.CurrentVersion == job.Version {
+		if err := s.repos.Dataset.UpdateIndexedAt(ctx, datasetID); err != nil {
+			return 0, fmt.Errorf("failed to update indexed_at: %w", err)
+		}
+	}
+
+	return len(chunks), nil
+}
+
+// dropOrphanVectors удаляет только что записанные точки, если датасет окончательно удалили
+// во время индексации: задача очистки могла отработать раньше. Датасет в корзине
+// сохраняет версии и точки, чтобы его можно было восстановить
+func (s *IndexServiceImpl) dropOrphanVectors(ctx context.Context, datasetID string, version int) {
+	if _, err := s.repos.DatasetVersion.GetByVersion(ctx, datasetID, version); err == nil || err.Error() != "version not found" {
+		return
+	}
+
+	if err := s.repos.Vector.DeleteByDatasetID(ctx, datasetID); err != nil {
+		logger.Warn(fmt.Sprintf("failed to delete vectors of removed dataset %s: %v", datasetID, err))
+	}
+}
+
+// contentHash — sha256 текста чанка, по нему переиндексация находит неизменившиеся точки
+func contentHash(text string) string {
+	sum := sha256.Sum256([]byte(text))
+	return hex.EncodeToString(sum[:])
+}
+
+// chunkerFor выбирает стратегию разбиения по теме датасета
+func (s *IndexServiceImpl) chunkerFor(ctx context.Context, dataset *domain.Dataset) (rag.Chunker, error) {
+	strategy := rag.ChunkingHeader
+	if dataset.TopicID != nil {
+		topic, err := s.repos.Topic.GetByID(ctx, *dataset.TopicID)
+		if err != nil && err.Error() != "topic not found" {
+			return nil, fmt.Errorf("failed to get topic: %w", err)
+		}
+		if topic != nil {
+			strategy = topic.ChunkingStrategy
+		}
+	}
+
+	chunker, err := rag.NewChunker(strategy, rag.ChunkOptions{
+		MaxTokens:     s.cfg.RAG.ChunkMaxTokens,
+		OverlapTokens: s.cfg.RAG.ChunkOverlapTokens,
+	})
+	if err != nil {
+		return nil, &permanentIndexError{err: err}
+	}
+
+	return chunker, nil
+}
+
+// reportProgress сохраняет прогресс задачи; ошибка записи не должна прерывать индексацию
+func (s *IndexServiceImpl) reportProgress(ctx context.Context, job *domain.IndexJob, embedded, total int) {
+	job.ChunksEmbedded = embedded
+	job.ChunksTotal = total
+	if err := s.repos.IndexJob.UpdateProgress(ctx, job.ID, embedded, total); err != nil {
+		logger.Error(fmt.Errorf("failed to report progress of index job %s: %w", job.ID, err))
+	}
+}
+
+func (s *IndexServiceImpl) GetStatus(ctx context.Context, dataset *domain.Dataset) (*domain.IndexStatusResponse, error) {
+	job, err := s.repos.IndexJob.GetLatestByDatasetID(ctx, dataset.ID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get index job: %w", err)
+	}
+
+	status := &domain.IndexStatusResponse{
+		DatasetID: dataset.ID,
+		Version:   dataset.CurrentVersion,
+		IndexedAt: dataset.IndexedAt,
+	}
+
+	if job == nil {
+		// датасеты, проиндексированные до появления очереди, задач не имеют
+		status.Status = domain.IndexStatusNotIndexed
+		if dataset.IndexedAt != nil {
+			status.Status = domain.IndexJobSucceeded
+		}
+		return status, nil
+	}
+
+	status.JobID = job.ID
+	status.Version = job.Version
+	status.Status = job.Status
+	status.ChunksEmbedded = job.ChunksEmbedded
+	status.ChunksTotal = job.ChunksTotal
+	status.Attempts = job.Attempts
+	status.MaxAttempts = job.MaxAttempts
+	status.LastError = job.LastError
+	status.UpdatedAt = &job.UpdatedAt
+	if job.Status == domain.IndexJobQueued {
+		status.RunAfter = &job.RunAfter
+	}
+
+	return status, nil
+}
@@ -0,0 +1,357 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+	"github.com/google/uuid"
+)
+
+const (
+	// datasetObjectPrefix — префикс ключей MinIO, под которым лежат версии датасетов
+	datasetObjectPrefix = "students/"
+	// reconcilePageSize — размер страницы при чтении датасетов из MySQL
+	reconcilePageSize = 500
+)
+
+// ReconcileServiceImpl сверяет MySQL, MinIO и Qdrant и находит расхождения, оставшиеся
+// от операций, прерванных между хранилищами
+type ReconcileServiceImpl struct {
+	repos *Repositories
+	cfg   *config.Config
+	index IndexService
+
+	running sync.Mutex
+	wg      sync.WaitGroup
+	// ctx — контекст воркеров: сверка, запущенная через API, переживает HTTP-запрос
+	// и останавливается вместе с остальными воркерами
+	ctx context.Context
+
+	mu   sync.Mutex
+	last *domain.ReconcileRun
+}
+
+func NewReconcileService(repos *Repositories, cfg *config.Config, index IndexService) *ReconcileServiceImpl {
+	return &ReconcileServiceImpl{
+		repos: repos,
+		cfg:   cfg,
+		index: index,
+		ctx:   context.Background(),
+	}
+}
+
+func (s *ReconcileServiceImpl) Start(ctx context.Context) {
+	s.ctx = ctx
+
+	if !s.cfg.Reconciler.Enabled || s.cfg.Reconciler.Interval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go s.scheduler(ctx)
+
+	logger.Info(fmt.Sprintf("Storage reconciler started: every %s", s.cfg.Reconciler.Interval))
+}
+
+func (s *ReconcileServiceImpl) Wait() {
+	s.wg.Wait()
+}
+
+func (s *ReconcileServiceImpl) scheduler(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Reconciler.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+
+		if !s.running.TryLock() {
+			logger.Warn("skipping scheduled storage reconciliation: previous run is still in progress")
+			continue
+		}
+		s.execute(ctx, s.begin(s.cfg.Reconciler.Apply), s.cfg.Reconciler.Apply)
+		s.running.Unlock()
+	}
+}
+
+// Launch запускает сверку в фоне и сразу возвращает её запуск. Полный обход хранилищ
+// занимает больше, чем живёт HTTP-запрос, поэтому отчёт забирается через LastRun
+func (s *ReconcileServiceImpl) Launch(apply bool) (*domain.ReconcileRun, error) {
+	if !s.running.TryLock() {
+		return nil, fmt.Errorf("reconciliation already running")
+	}
+
+	run := s.begin(apply)
+
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		defer s.running.Unlock()
+
+		s.execute(s.ctx, run, apply)
+	}()
+
+	return s.LastRun(), nil
+}
+
+// LastRun возвращает текущую или последнюю завершённую сверку, nil если сверок ещё не было
+func (s *ReconcileServiceImpl) LastRun() *domain.ReconcileRun {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil {
+		return nil
+	}
+	run := *s.last
+	return &run
+}
+
+// begin регистрирует новый запуск как текущий. Вызывается под s.running
+func (s *ReconcileServiceImpl) begin(apply bool) string {
+	run := &domain.ReconcileRun{
+		ID:        uuid.New().String(),
+		Mode:      domain.ReconcileDryRun,
+		Status:    domain.ReconcileRunning,
+		StartedAt: time.Now(),
+	}
+	if apply {
+		run.Mode = domain.ReconcileApply
+	}
+
+	s.mu.Lock()
+	s.last = run
+	s.mu.Unlock()
+
+	return run.ID
+}
+
+// execute выполняет сверку запуска id и сохраняет её результат. Вызывается под s.running
+func (s *ReconcileServiceImpl) execute(ctx context.Context, id string, apply bool) {
+	report, err := s.run(ctx, apply)
+	if err != nil {
+		logger.Error(fmt.Errorf("storage reconciliation failed: %w", err))
+	} else {
+		s.log(report)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.last == nil || s.last.ID != id {
+		return
+	}
+	finished := time.Now()
+	run := *s.last
+	run.FinishedAt = &finished
+	if err != nil {
+		run.Status = domain.ReconcileFailed
+		run.Error = err.Error()
+	} else {
+		run.Status = domain.ReconcileCompleted
+		run.Report = report
+	}
+	s.last = &run
+}
+
+// run выполняет одну сверку. В режиме dry run только собирает отчёт, при apply исправляет
+// всё, что можно исправить: удаляет бесхозные объекты и точки, переиндексирует версии без точек
+func (s *ReconcileServiceImpl) run(ctx context.Context, apply bool) (*domain.ReconcileReport, error) {
+	report := &domain.ReconcileReport{
+		Mode:           domain.ReconcileDryRun,
+		StartedAt:      time.Now(),
+		OrphanObjects:  make([]string, 0),
+		OrphanVectors:  make([]string, 0),
+		MissingVectors: make([]domain.ReconcileVersion, 0),
+		MissingObjects: make([]string, 0),
+	}
+	if apply {
+		report.Mode = domain.ReconcileApply
+	}
+
+	// MySQL читается первым: объект загружается раньше, чем появляется строка,
+	// а точки пишутся раньше indexed_at, поэтому всё, что видно в MySQL, уже есть в хранилищах
+	datasets, err := s.loadDatasets(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := s.repos.DatasetVersion.GetAll(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get dataset versions: %w", err)
+	}
+	report.Datasets = len(datasets)
+
+	objects, err := s.repos.File.List(ctx, datasetObjectPrefix)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list objects: %w", err)
+	}
+	report.Objects = len(objects)
+
+	indexed, err := s.repos.Vector.ListVersions(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list vectors: %w", err)
+	}
+
+	s.checkObjects(ctx, report, datasets, versions, objects, apply)
+	s.checkOrphanVectors(ctx, report, datasets, versions, indexed, apply)
+	s.checkMissingVectors(ctx, report, datasets, versions, indexed, apply)
+
+	report.FinishedAt = time.Now()
+	return report, nil
+}
+
+func (s *ReconcileServiceImpl) loadDatasets(ctx context.Context) (map[string]*domain.Dataset, error) {
+	datasets := make(map[string]*domain.Dataset)
+	for offset := 0; ; offset += reconcilePageSize {
+		page, total, err := s.repos.Dataset.GetAll(ctx, offset, reconcilePageSize)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get datasets: %w", err)
+		}
+		for i := range page {
+			datasets[page[i].ID] = &page[i]
+		}
+		if len(page) == 0 || offset+len(page) >= total {
+			return datasets, nil
+		}
+	}
+}
+
+// checkObjects ищет объекты без строки в MySQL и строки без объекта
+func (s *ReconcileServiceImpl) checkObjects(ctx context.Context, report *domain.ReconcileReport, datasets map[string]*domain.Dataset, versions []domain.DatasetVersion, objects []domain.FileInfo, apply bool) {
+	referenced := make(map[string]bool, len(versions)+len(datasets))
+	for _, d := range datasets {
+		referenced[d.FilePath] = true
+	}
+	for _, v := range versions {
+		referenced[v.FilePath] = true
+	}
+
+	stored := make(map[string]bool, len(objects))
+	cutoff := time.Now().Add(-s.cfg.Reconciler.GracePeriod)
+	for _, object := range objects {
+		stored[object.Path] = true
+		if referenced[object.Path] || object.ModifiedAt.After(cutoff) {
+			continue
+		}
+
+		report.OrphanObjects = append(report.OrphanObjects, object.Path)
+		if apply {
+			if err := s.repos.File.Delete(ctx, object.Path); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to delete object %s: %v", object.Path, err))
+				continue
+			}
+			report.Repaired++
+		}
+	}
+
+	for path := range referenced {
+		if !stored[path] {
+			report.MissingObjects = append(report.MissingObjects, path)
+		}
+	}
+}
+
+// checkOrphanVectors ищет точки датасетов, которых нет в MySQL. Датасеты в корзине
+// не попадают в список, но их версии остаются, поэтому существование проверяется по версиям
+func (s *ReconcileServiceImpl) checkOrphanVectors(ctx context.Context, report *domain.ReconcileReport, datasets map[string]*domain.Dataset, versions []domain.DatasetVersion, indexed []domain.SearchTarget, apply bool) {
+	known := make(map[string]bool, len(datasets))
+	for id := range datasets {
+		known[id] = true
+	}
+	for _, v := range versions {
+		known[v.DatasetID] = true
+	}
+
+	orphans := make(map[string]bool)
+	for _, target := range indexed {
+		if !known[target.DatasetID] && !orphans[target.DatasetID] {
+			orphans[target.DatasetID] = true
+			report.OrphanVectors = append(report.OrphanVectors, target.DatasetID)
+		}
+	}
+
+	if !apply {
+		return
+	}
+
+	for _, datasetID := range report.OrphanVectors {
+		// датасет мог появиться уже после чтения MySQL
+		existing, err := s.repos.DatasetVersion.GetByDatasetID(ctx, datasetID)
+		if err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to check dataset %s: %v", datasetID, err))
+			continue
+		}
+		if len(existing) > 0 {
+			continue
+		}
+
+		if err := s.repos.Vector.DeleteByDatasetID(ctx, datasetID); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to delete vectors of dataset %s: %v", datasetID, err))
+			continue
+		}
+		if s.cfg.RAG.AnswerCache.Enabled {
+			if err := s.repos.AnswerCache.DeleteByDatasetID(ctx, datasetID); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to delete cached answers of dataset %s: %v", datasetID, err))
+			}
+		}
+		report.Repaired++
+	}
+}
+
+// checkMissingVectors ищет версии с indexed_at, для которых в Qdrant нет ни одной точки.
+// Такие версии ставятся на переиндексацию, у текущей версии indexed_at сбрасывается,
+// чтобы вопросы к датасету не уходили в пустой индекс
+func (s *ReconcileServiceImpl) checkMissingVectors(ctx context.Context, report *domain.ReconcileReport, datasets map[string]*domain.Dataset, versions []domain.DatasetVersion, indexed []domain.SearchTarget, apply bool) {
+	present := make(map[domain.SearchTarget]bool, len(indexed))
+	for _, target := range indexed {
+		present[target] = true
+	}
+
+	for _, v := range versions {
+		dataset := datasets[v.DatasetID]
+		if dataset == nil || v.IndexedAt == nil || present[domain.SearchTarget{DatasetID: v.DatasetID, Version: v.Version}] {
+			continue
+		}
+
+		report.MissingVectors = append(report.MissingVectors, domain.ReconcileVersion{DatasetID: v.DatasetID, Version: v.Version})
+		if !apply {
+			continue
+		}
+
+		if dataset.CurrentVersion == v.Version && dataset.IndexedAt != nil {
+			if err := s.repos.Dataset.SetIndexedAt(ctx, dataset.ID, nil); err != nil {
+				report.Errors = append(report.Errors, fmt.Sprintf("failed to reset indexed_at of dataset %s: %v", dataset.ID, err))
+				continue
+			}
+		}
+		if _, err := s.index.Enqueue(ctx, v.DatasetID, v.Version); err != nil {
+			report.Errors = append(report.Errors, fmt.Sprintf("failed to reindex dataset %s version %d: %v", v.DatasetID, v.Version, err))
+			continue
+		}
+		report.Repaired++
+	}
+}
+
+func (s *ReconcileServiceImpl) log(report *domain.ReconcileReport) {
+	message := fmt.Sprintf("storage reconciliation (%s): %d orphan objects, %d orphan vector sets, %d versions without vectors, %d missing objects, %d repaired",
+		report.Mode, len(report.OrphanObjects), len(report.OrphanVectors), len(report.MissingVectors), len(report.MissingObjects), report.Repaired)
+
+	if len(report.OrphanObjects)+len(report.OrphanVectors)+len(report.MissingVectors)+len(report.MissingObjects) == 0 {
+		logger.Info(message)
+		return
+	}
+	logger.Warn(message)
+
+	for _, e := range report.Errors {
+		logger.Warn(e)
+	}
+}
@@ -0,0 +1,232 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/client/embedding"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// lexicalCacheSize — сколько BM25-индексов версий датасетов держать в памяти
+const lexicalCacheSize = 64
+
+type lexicalIndex struct {
+	bm25   *rag.BM25Index
+	chunks []domain.ChunkData
+}
+
+// lexicalIndexCache хранит BM25-индексы по ключу датасет+версия+время индексации,
+// поэтому переиндексация той же версии автоматически даёт новый ключ
+type lexicalIndexCache struct {
+	mu      sync.Mutex
+	entries map[string]*lexicalIndex
+	order   []string
+}
+
+func newLexicalIndexCache() *lexicalIndexCache {
+	return &lexicalIndexCache{
+		entries: make(map[string]*lexicalIndex),
+	}
+}
+
+func (c *lexicalIndexCache) get(key string) (*lexicalIndex, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	idx, ok := c.entries[key]
+	return idx, ok
+}
+
+func (c *lexicalIndexCache) put(key string, idx *lexicalIndex) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, ok := c.entries[key]; ok {
+		return
+	}
+
+	if len(c.order) >= lexicalCacheSize {
+		delete(c.entries, c.order[0])
+		c.order = c.order[1:]
+	}
+
+	c.entries[key] = idx
+	c.order = append(c.order, key)
+}
+
+// searchTarget — проиндексированная версия датасета, по которой идёт поиск
+type searchTarget struct {
+	dataset   *domain.Dataset
+	version   int
+	indexedAt time.Time
+}
+
+type hitKey struct {
+	datasetID string
+	chunkID   int
+}
+
+// hybridSearch объединяет векторный поиск и BM25 через reciprocal rank fusion.
+// Если лексический индекс недоступен, возвращаются результаты векторного поиска
+func (s *DatasetServiceImpl) hybridSearch(ctx context.Context, targets []searchTarget, question string, queryVector []float32) ([]domain.SearchHit, error) {
+	topK := s.cfg.RAG.SearchTopK
+
+	vectorTargets := make([]domain.SearchTarget, len(targets))
+	for i, t := range targets {
+		vectorTargets[i] = domain.SearchTarget{DatasetID: t.dataset.ID, Version: t.version}
+	}
+
+	dense, err := s.repos.Vector.Search(ctx, vectorTargets, queryVector, uint64(topK))
+	if err != nil {
+		return nil, err
+	}
+
+	if s.cfg.RAG.SparseWeight <= 0 {
+		return dense, nil
+	}
+
+	sparse, err := s.lexicalSearch(ctx, targets, question, topK)
+	if err != nil {
+		logger.Warn(fmt.Sprintf("lexical search unavailable: %v", err))
+		return dense, nil
+	}
+
+	// RRF работает с целочисленными идентификаторами, поэтому чанки нумеруются по ключу датасет+чанк
+	ids := make(map[hitKey]int)
+	hits := make([]domain.SearchHit, 0, len(dense)+len(sparse))
+	rank := func(list []domain.SearchHit) []int {
+		ranked := make([]int, len(list))
+		for i, hit := range list {
+			key := hitKey{datasetID: hit.DatasetID, chunkID: hit.ChunkID}
+			id, ok := ids[key]
+			if !ok {
+				id = len(hits)
+				ids[key] = id
+				hits = append(hits, hit)
+			}
+			ranked[i] = id
+		}
+		return ranked
+	}
+
+	fused := rag.ReciprocalRankFusion(s.cfg.RAG.RRFK,
+		rag.RankedList{IDs: rank(dense), Weight: s.cfg.RAG.DenseWeight},
+		rag.RankedList{IDs: rank(sparse), Weight: s.cfg.RAG.SparseWeight},
+	)
+
+	result := make([]domain.SearchHit, 0, min(len(fused), topK))
+	for _, scored := range fused {
+		if len(result) == topK {
+			break
+		}
+		result = append(result, hits[scored.Index])
+	}
+
+	return result, nil
+}
+
+// rerank упорядочивает найденные фрагменты по убыванию релевантности.
+// Без реранкера сохраняется порядок гибридного поиска, а оценкой служит оценка векторного поиска
+func (s *DatasetServiceImpl) rerank(ctx context.Context, query string, hits []domain.SearchHit) ([]embedding.RerankResult, error) {
+	if s.clients.Reranker == nil {
+		results := make([]embedding.RerankResult, len(hits))
+		for i, hit := range hits {
+			results[i] = embedding.RerankResult{Index: i, Score: float64(hit.Score)}
+		}
+		return results, nil
+	}
+
+	texts := make([]string, len(hits))
+	for i, hit := range hits {
+		texts[i] = hit.Text
+	}
+
+	results, err := s.clients.Reranker.Rerank(ctx, query, texts)
+	if err != nil {
+		return nil, err
+	}
+
+	sort.Slice(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+
+	return results, nil
+}
+
+// lexicalSearch ищет по BM25-индексу каждой версии и сливает результаты по оценке
+func (s *DatasetServiceImpl) lexicalSearch(ctx context.Context, targets []searchTarget, question string, topK int) ([]domain.SearchHit, error) {
+	type scoredHit struct {
+		hit   domain.SearchHit
+		score float64
+	}
+
+	scored := make([]scoredHit, 0)
+	for _, t := range targets {
+		lexical, err := s.lexicalIndex(ctx, t.dataset.ID, t.version, t.indexedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, res := range lexical.bm25.Search(question, topK) {
+			chunk := lexical.chunks[res.Index]
+			scored = append(scored, scoredHit{
+				hit: domain.SearchHit{
+					DatasetID:    t.dataset.ID,
+					Version:      t.version,
+					ChunkID:      chunk.Index,
+					Title:        t.dataset.Title,
+					SectionTitle: chunk.SectionTitle,
+					Text:         chunk.Text,
+					StartOffset:  chunk.StartOffset,
+					EndOffset:    chunk.EndOffset,
+				},
+				score: res.Score,
+			})
+		}
+	}
+
+	sort.SliceStable(scored, func(i, j int) bool {
+		return scored[i].score > scored[j].score
+	})
+
+	hits := make([]domain.SearchHit, 0, min(len(scored), topK))
+	for _, sh := range scored {
+		if len(hits) == topK {
+			break
+		}
+		hits = append(hits, sh.hit)
+	}
+
+	return hits, nil
+}
+
+func (s *DatasetServiceImpl) lexicalIndex(ctx context.Context, datasetID string, version int, indexedAt time.Time) (*lexicalIndex, error) {
+	key := fmt.Sprintf("%s:%d:%d", datasetID, version, indexedAt.UnixNano())
+	if idx, ok := s.lexical.get(key); ok {
+		return idx, nil
+	}
+
+	chunks, err := s.repos.Vector.ListChunks(ctx, datasetID, version)
+	if err != nil {
+		return nil, err
+	}
+
+	texts := make([]string, len(chunks))
+	for i, chunk := range chunks {
+		texts[i] = chunk.Text
+	}
+
+	idx := &lexicalIndex{
+		bm25:   rag.NewBM25Index(texts),
+		chunks: chunks,
+	}
+	s.lexical.put(key, idx)
+
+	return idx, nil
+}
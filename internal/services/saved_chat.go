@@ -204,11 +204,11 @@ func (s *SavedChatServiceImpl) DeleteChat(ctx context.Context, chatID, userID, r
 		return fmt.Errorf("access denied: only creator or admin can delete chat")
 	}
 
-	if err := s.repos.SavedChat.Delete(ctx, chatID); err != nil {
+	if err := s.repos.SavedChat.SoftDelete(ctx, chatID, userID); err != nil {
 		return fmt.Errorf("failed to delete chat: %w", err)
 	}
 
-	logger.Info(fmt.Sprintf("chat %s deleted by user %s", chatID, userID))
+	logger.Info(fmt.Sprintf("chat %s moved to trash by user %s", chatID, userID))
 	return nil
 }
 
@@ -4,21 +4,31 @@ import (
 	"context"
 	"io"
 
+	"github.com/anton1ks96/college-core-api/internal/client/embedding"
 	"github.com/anton1ks96/college-core-api/internal/client/llm"
-	"github.com/anton1ks96/college-core-api/internal/client/tei"
 	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/domain"
 	"github.com/anton1ks96/college-core-api/internal/repository"
 )
 
 type DatasetService interface {
-	Create(ctx context.Context, userID, username, title, assignmentID string, content io.Reader) (*domain.Dataset, error)
+	Create(ctx context.Context, userID, username, title, assignmentID string, content io.Reader, size int64) (*domain.Dataset, error)
 	GetByID(ctx context.Context, datasetID, userID string, role string) (*domain.DatasetResponse, error)
+	OpenFile(ctx context.Context, datasetID, userID, role string) (io.ReadSeekCloser, *domain.FileInfo, error)
 	GetList(ctx context.Context, userID string, role string, page, limit int) (*domain.DatasetListResponse, error)
-	Update(ctx context.Context, datasetID, userID, title string, content *string) (*domain.Dataset, error)
+	Update(ctx context.Context, datasetID, userID, username, title string, content *string) (*domain.Dataset, error)
 	Delete(ctx context.Context, datasetID, userID, role string) error
-	AskQuestion(ctx context.Context, datasetID, userID, role, question string) (<-chan domain.AskEvent, error)
-	Reindex(ctx context.Context, datasetID, userID string) (*domain.IndexResponse, error)
+	GetVersions(ctx context.Context, datasetID, userID, role string) (*domain.DatasetVersionListResponse, error)
+	GetVersion(ctx context.Context, datasetID, userID, role string, version int) (*domain.DatasetVersionResponse, error)
+	SetCurrentVersion(ctx context.Context, datasetID, userID string, version int) (*domain.Dataset, error)
+	GetChunks(ctx context.Context, datasetID, userID, role string, version int) (*domain.DatasetChunksResponse, error)
+	GetDiff(ctx context.Context, datasetID, userID, role string, from, to int) (*domain.DatasetDiffResponse, error)
+	GetDiffMarkdown(ctx context.Context, datasetID, userID, role string, from, to int) ([]byte, error)
+	AskQuestion(ctx context.Context, datasetID, userID, role string, req domain.AskRequest) (<-chan domain.AskEvent, error)
+	AskMultiple(ctx context.Context, userID, role string, req domain.MultiAskRequest) (<-chan domain.AskEvent, error)
+	Reindex(ctx context.Context, datasetID, userID string) (*domain.IndexJob, error)
+	GetIndexStatus(ctx context.Context, datasetID, userID, role string) (*domain.IndexStatusResponse, error)
+	WatchIndexStatus(ctx context.Context, datasetID, userID, role string) (<-chan domain.IndexStatusResponse, error)
 	SetTag(ctx context.Context, datasetID, userID, role string, tag *string) error
 	SearchByTag(ctx context.Context, userID, role, tag string, page, limit int) (*domain.DatasetListResponse, error)
 }
@@ -30,7 +40,9 @@ type AuthService interface {
 type TopicService interface {
 	SearchStudents(ctx context.Context, query string) ([]domain.StudentInfo, int, error)
 	SearchTeachers(ctx context.Context, query string) ([]domain.StudentInfo, int, error)
-	CreateTopic(ctx context.Context, userID, userName, title, description string, students []domain.StudentInfo) (*domain.Topic, error)
+	CreateTopic(ctx context.Context, userID, userName, title, description, chunkingStrategy string, students []domain.StudentInfo) (*domain.Topic, error)
+	UpdateChunkingStrategy(ctx context.Context, topicID, userID, role, strategy string) (*domain.Topic, error)
+	DeleteTopic(ctx context.Context, topicID, userID, role string) error
 	GetMyTopics(ctx context.Context, userID string, page, limit int) ([]domain.Topic, int, error)
 	GetAllTopics(ctx context.Context, page, limit int) ([]domain.Topic, int, error)
 	GetAssignedTopics(ctx context.Context, studentID string) ([]domain.AssignedTopicResponse, error)
@@ -55,26 +67,72 @@ type SavedChatService interface {
 	DownloadChatMarkdown(ctx context.Context, chatID, userID, role string) ([]byte, string, error)
 }
 
+type IndexService interface {
+	Enqueue(ctx context.Context, datasetID string, version int) (*domain.IndexJob, error)
+	GetStatus(ctx context.Context, dataset *domain.Dataset) (*domain.IndexStatusResponse, error)
+	Start(ctx context.Context)
+	Wait()
+}
+
+type CleanupService interface {
+	Notify()
+	Start(ctx context.Context)
+	Wait()
+}
+
+type EmbeddingCacheService interface {
+	Start(ctx context.Context)
+	Wait()
+}
+
+type ReconcileService interface {
+	Launch(apply bool) (*domain.ReconcileRun, error)
+	LastRun() *domain.ReconcileRun
+	Start(ctx context.Context)
+	Wait()
+}
+
+type TrashService interface {
+	List(ctx context.Context, itemType string, page, limit int) (*domain.TrashListResponse, error)
+	Restore(ctx context.Context, itemType, id, userID string) error
+	Start(ctx context.Context)
+	Wait()
+}
+
 type Services struct {
 	Dataset           DatasetService
 	Auth              AuthService
 	Topic             TopicService
 	DatasetPermission DatasetPermissionService
 	SavedChat         SavedChatService
+	Index             IndexService
+	Cleanup           CleanupService
+	EmbeddingCache    EmbeddingCacheService
+	Reconcile         ReconcileService
+	Trash             TrashService
 }
 
 type Repositories struct {
 	Dataset           repository.DatasetRepository
+	DatasetVersion    repository.DatasetVersionRepository
 	File              repository.FileRepository
 	Topic             repository.TopicRepository
 	DatasetPermission repository.DatasetPermissionRepository
 	SavedChat         repository.SavedChatRepository
 	Vector            repository.VectorRepository
+	IndexJob          repository.IndexJobRepository
+	CleanupTask       repository.CleanupTaskRepository
+	Usage             repository.UsageRepository
+	AnswerCache       repository.AnswerCacheRepository
+	// EmbeddingCache равен nil, если кэш эмбеддингов выключен
+	EmbeddingCache repository.EmbeddingCacheRepository
 }
 
+// Clients — внешние модели. Reranker может быть nil, тогда используется порядок гибридного поиска
 type Clients struct {
-	LLM *llm.Client
-	TEI *tei.Client
+	LLM      llm.LLM
+	Embedder embedding.Embedder
+	Reranker embedding.Reranker
 }
 
 type Deps struct {
@@ -85,10 +143,15 @@ type Deps struct {
 
 func NewServices(deps Deps) *Services {
 	authService := NewAuthService(deps.Config)
-	datasetService := NewDatasetService(deps.Repos, deps.Clients, deps.Config)
-	topicService := NewTopicService(deps.Repos, deps.Config)
+	indexService := NewIndexService(deps.Repos, deps.Clients, deps.Config)
+	cleanupService := NewCleanupService(deps.Repos, deps.Config)
+	embeddingCacheService := NewEmbeddingCacheService(deps.Repos, deps.Config)
+	datasetService := NewDatasetService(deps.Repos, deps.Clients, deps.Config, indexService)
+	topicService := NewTopicService(deps.Repos, deps.Config, indexService)
 	datasetPermissionService := NewDatasetPermissionService(deps.Repos)
 	savedChatService := NewSavedChatService(deps.Repos)
+	reconcileService := NewReconcileService(deps.Repos, deps.Config, indexService)
+	trashService := NewTrashService(deps.Repos, deps.Config, cleanupService)
 
 	return &Services{
 		Dataset:           datasetService,
@@ -96,6 +159,11 @@ func NewServices(deps Deps) *Services {
 		Topic:             topicService,
 		DatasetPermission: datasetPermissionService,
 		SavedChat:         savedChatService,
+		Index:             indexService,
+		Cleanup:           cleanupService,
+		EmbeddingCache:    embeddingCacheService,
+		Reconcile:         reconcileService,
+		Trash:             trashService,
 	}
 }
 
@@ -10,6 +10,7 @@ import (
 
 	"github.com/anton1ks96/college-core-api/internal/config"
 	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
 	"github.com/anton1ks96/college-core-api/pkg/logger"
 	"github.com/google/uuid"
 )
@@ -17,13 +18,15 @@ import (
 type TopicServiceImpl struct {
 	repos      *Repositories
 	cfg        *config.Config
+	index      IndexService
 	httpClient *http.Client
 }
 
-func NewTopicService(repos *Repositories, cfg *config.Config) *TopicServiceImpl {
+func NewTopicService(repos *Repositories, cfg *config.Config, index IndexService) *TopicServiceImpl {
 	return &TopicServiceImpl{
 		repos: repos,
 		cfg:   cfg,
+		index: index,
 		httpClient: &http.Client{
 			Timeout: cfg.AuthService.Timeout,
 		},
@@ -112,12 +115,17 @@ func (s *TopicServiceImpl) SearchTeachers(ctx context.Context, query string) ([]
 	return response.Students, response.Total, nil
 }
 
-func (s *TopicServiceImpl) CreateTopic(ctx context.Context, userID, userName, title, description string, students []domain.StudentInfo) (*domain.Topic, error) {
+func (s *TopicServiceImpl) CreateTopic(ctx context.Context, userID, userName, title, description, chunkingStrategy string, students []domain.StudentInfo) (*domain.Topic, error) {
+	if chunkingStrategy == "" {
+		chunkingStrategy = rag.ChunkingHeader
+	}
+
 	topic := &domain.Topic{
-		Title:       title,
-		Description: description,
-		CreatedBy:   userName,
-		CreatedByID: userID,
+		Title:            title,
+		Description:      description,
+		CreatedBy:        userName,
+		CreatedByID:      userID,
+		ChunkingStrategy: chunkingStrategy,
 	}
 
 	if err := s.repos.Topic.Create(ctx, topic); err != nil {
@@ -148,6 +156,65 @@ func (s *TopicServiceImpl) CreateTopic(ctx context.Context, userID, userName, ti
 	return topic, nil
 }
 
+// DeleteTopic переносит тему в корзину. Датасеты студентов остаются доступны
+func (s *TopicServiceImpl) DeleteTopic(ctx context.Context, topicID, userID, role string) error {
+	topic, err := s.repos.Topic.GetByID(ctx, topicID)
+	if err != nil {
+		return err
+	}
+
+	if role != "admin" && topic.CreatedByID != userID {
+		return fmt.Errorf("access denied: only topic creator or admin can delete topic")
+	}
+
+	if err := s.repos.Topic.SoftDelete(ctx, topicID, userID); err != nil {
+		return fmt.Errorf("failed to delete topic: %w", err)
+	}
+
+	logger.Info(fmt.Sprintf("topic %s moved to trash by user %s", topicID, userID))
+	return nil
+}
+
+// UpdateChunkingStrategy меняет стратегию разбиения темы и ставит на переиндексацию
+// все её датасеты, чтобы они были разбиты одинаково
+func (s *TopicServiceImpl) UpdateChunkingStrategy(ctx context.Context, topicID, userID, role, strategy string) (*domain.Topic, error) {
+	topic, err := s.repos.Topic.GetByID(ctx, topicID)
+	if err != nil {
+		return nil, err
+	}
+
+	if role != "admin" && topic.CreatedByID != userID {
+		return nil, fmt.Errorf("access denied: only topic creator or admin can change chunking strategy")
+	}
+
+	if !rag.IsValidChunkingStrategy(strategy) {
+		return nil, fmt.Errorf("unknown chunking strategy: %s", strategy)
+	}
+
+	if topic.ChunkingStrategy == strategy {
+		return topic, nil
+	}
+
+	if err := s.repos.Topic.UpdateChunkingStrategy(ctx, topicID, strategy); err != nil {
+		return nil, fmt.Errorf("failed to update chunking strategy: %w", err)
+	}
+	topic.ChunkingStrategy = strategy
+
+	datasets, err := s.repos.Dataset.GetByTopicID(ctx, topicID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get topic datasets: %w", err)
+	}
+
+	for _, dataset := range datasets {
+		if _, err := s.index.Enqueue(ctx, dataset.ID, dataset.CurrentVersion); err != nil {
+			logger.Error(fmt.Errorf("failed to queue reindexing for dataset %s: %w", dataset.ID, err))
+		}
+	}
+
+	logger.Info(fmt.Sprintf("topic %s chunking strategy set to %s, %d datasets queued for reindexing", topicID, strategy, len(datasets)))
+	return topic, nil
+}
+
 func (s *TopicServiceImpl) GetMyTopics(ctx context.Context, userID string, page, limit int) ([]domain.Topic, int, error) {
 	if page < 1 {
 		page = 1
@@ -0,0 +1,222 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/config"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// purgeBatchSize — сколько датасетов окончательно удаляется за один проход
+const purgeBatchSize = 100
+
+// TrashServiceImpl показывает и восстанавливает удалённые датасеты, темы и чаты,
+// а по истечении срока хранения удаляет их окончательно
+type TrashServiceImpl struct {
+	repos   *Repositories
+	cfg     *config.Config
+	cleanup CleanupService
+	wg      sync.WaitGroup
+}
+
+func NewTrashService(repos *Repositories, cfg *config.Config, cleanup CleanupService) *TrashServiceImpl {
+	return &TrashServiceImpl{
+		repos:   repos,
+		cfg:     cfg,
+		cleanup: cleanup,
+	}
+}
+
+func (s *TrashServiceImpl) List(ctx context.Context, itemType string, page, limit int) (*domain.TrashListResponse, error) {
+	if page < 1 {
+		page = 1
+	}
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	var (
+		items []domain.TrashItem
+		total int
+		err   error
+	)
+	switch itemType {
+	case domain.TrashDataset:
+		items, total, err = s.repos.Dataset.GetDeleted(ctx, offset, limit)
+	case domain.TrashTopic:
+		items, total, err = s.repos.Topic.GetDeleted(ctx, offset, limit)
+	case domain.TrashChat:
+		items, total, err = s.repos.SavedChat.GetDeleted(ctx, offset, limit)
+	default:
+		return nil, fmt.Errorf("unknown trash type")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get trash: %w", err)
+	}
+
+	if items == nil {
+		items = make([]domain.TrashItem, 0)
+	}
+	for i := range items {
+		items[i].Type = itemType
+		items[i].PurgeAt = items[i].DeletedAt.Add(s.cfg.Trash.Retention)
+	}
+
+	return &domain.TrashListResponse{
+		Items: items,
+		Total: total,
+		Page:  page,
+		Limit: limit,
+	}, nil
+}
+
+func (s *TrashServiceImpl) Restore(ctx context.Context, itemType, id, userID string) error {
+	var err error
+	switch itemType {
+	case domain.TrashDataset:
+		err = s.restoreDataset(ctx, id)
+	case domain.TrashTopic:
+		err = s.repos.Topic.Restore(ctx, id)
+	case domain.TrashChat:
+		err = s.repos.SavedChat.Restore(ctx, id)
+	default:
+		return fmt.Errorf("unknown trash type")
+	}
+	if err != nil {
+		return err
+	}
+
+	logger.Info(fmt.Sprintf("%s %s restored from trash by user %s", itemType, id, userID))
+	return nil
+}
+
+// restoreDataset не даёт восстановить датасет, если студент уже загрузил новый по той же теме
+func (s *TrashServiceImpl) restoreDataset(ctx context.Context, id string) error {
+	dataset, err := s.repos.Dataset.GetDeletedByID(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if dataset.TopicID != nil {
+		exists, err := s.repos.Dataset.ExistsByUserIDAndTopicID(ctx, dataset.UserID, *dataset.TopicID)
+		if err != nil {
+			return fmt.Errorf("failed to check existing dataset: %w", err)
+		}
+		if exists {
+			return fmt.Errorf("dataset already exists for this topic")
+		}
+	}
+
+	return s.repos.Dataset.Restore(ctx, id)
+}
+
+func (s *TrashServiceImpl) Start(ctx context.Context) {
+	if s.cfg.Trash.Retention <= 0 || s.cfg.Trash.PurgeInterval <= 0 {
+		return
+	}
+
+	s.wg.Add(1)
+	go s.purger(ctx)
+
+	logger.Info(fmt.Sprintf("Trash purger started: retention %s", s.cfg.Trash.Retention))
+}
+
+func (s *TrashServiceImpl) Wait() {
+	s.wg.Wait()
+}
+
+func (s *TrashServiceImpl) purger(ctx context.Context) {
+	defer s.wg.Done()
+
+	ticker := time.NewTicker(s.cfg.Trash.PurgeInterval)
+	defer ticker.Stop()
+
+	for {
+		s.purge(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// purge окончательно удаляет записи старше срока хранения. Чаты удаляются раньше датасетов,
+// хотя каскад по датасету всё равно убрал бы их. Темы удаляются последними: тема, на которую
+// ссылается хоть один датасет, остаётся в корзине до его окончательного удаления
+func (s *TrashServiceImpl) purge(ctx context.Context) {
+	before := time.Now().Add(-s.cfg.Trash.Retention)
+
+	if n, err := s.repos.SavedChat.PurgeDeleted(ctx, before); err != nil {
+		logger.Error(fmt.Errorf("failed to purge deleted chats: %w", err))
+	} else if n > 0 {
+		logger.Info(fmt.Sprintf("purged %d deleted chats", n))
+	}
+
+	for ctx.Err() == nil {
+		datasets, err := s.repos.Dataset.GetDeletedBefore(ctx, before, purgeBatchSize)
+		if err != nil {
+			logger.Error(fmt.Errorf("failed to get expired datasets: %w", err))
+			break
+		}
+
+		purged := 0
+		for i := range datasets {
+			if err := s.purgeDataset(ctx, &datasets[i]); err != nil {
+				// датасет восстановили между выборкой и удалением
+				if err.Error() == "item not found in trash" {
+					continue
+				}
+				logger.Error(fmt.Errorf("failed to purge dataset %s: %w", datasets[i].ID, err))
+				continue
+			}
+			purged++
+		}
+		if purged > 0 {
+			logger.Info(fmt.Sprintf("purged %d deleted datasets", purged))
+			s.cleanup.Notify()
+		}
+
+		// если ни один датасет не удалился, следующая страница будет той же самой
+		if len(datasets) < purgeBatchSize || purged == 0 {
+			break
+		}
+	}
+
+	if n, err := s.repos.Topic.PurgeDeleted(ctx, before); err != nil {
+		logger.Error(fmt.Errorf("failed to purge deleted topics: %w", err))
+	} else if n > 0 {
+		logger.Info(fmt.Sprintf("purged %d deleted topics", n))
+	}
+}
+
+// purgeDataset удаляет строку датасета, а объекты и векторы передаёт фоновому воркеру очистки:
+// задачи сохраняются вместе с удалением строки и повторяются, пока MinIO и Qdrant не подтвердят удаление
+func (s *TrashServiceImpl) purgeDataset(ctx context.Context, dataset *domain.Dataset) error {
+	versions, err := s.repos.DatasetVersion.GetByDatasetID(ctx, dataset.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get dataset versions: %w", err)
+	}
+
+	tasks := make([]domain.CleanupTask, 0, len(versions)+3)
+	paths := map[string]bool{dataset.FilePath: true}
+	tasks = append(tasks, domain.CleanupTask{Kind: domain.CleanupFile, Target: dataset.FilePath})
+	for _, v := range versions {
+		if !paths[v.FilePath] {
+			paths[v.FilePath] = true
+			tasks = append(tasks, domain.CleanupTask{Kind: domain.CleanupFile, Target: v.FilePath})
+		}
+	}
+	tasks = append(tasks, domain.CleanupTask{Kind: domain.CleanupVectors})
+	if s.cfg.RAG.AnswerCache.Enabled {
+		tasks = append(tasks, domain.CleanupTask{Kind: domain.CleanupAnswerCache})
+	}
+
+	return s.repos.Dataset.DeleteWithCleanup(ctx, dataset.ID, tasks)
+}
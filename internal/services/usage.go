@@ -0,0 +1,129 @@
+package services
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/anton1ks96/college-core-api/internal/client/llm"
+	"github.com/anton1ks96/college-core-api/internal/domain"
+	"github.com/anton1ks96/college-core-api/internal/rag"
+	"github.com/anton1ks96/college-core-api/pkg/logger"
+)
+
+// checkQuota проверяет дневной и месячный лимиты токенов роли пользователя
+func (s *DatasetServiceImpl) checkQuota(ctx context.Context, userID, role string) error {
+	quota, ok := s.cfg.Limits.TokenQuotas[role]
+	if !ok {
+		return nil
+	}
+
+	now := time.Now()
+	if quota.Daily > 0 {
+		used, err := s.repos.Usage.SumTokensSince(ctx, userID, time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location()))
+		if err != nil {
+			return fmt.Errorf("failed to check token quota: %w", err)
+		}
+		if used >= quota.Daily {
+			return fmt.Errorf("daily token quota exceeded")
+		}
+	}
+
+	if quota.Monthly > 0 {
+		used, err := s.repos.Usage.SumTokensSince(ctx, userID, time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location()))
+		if err != nil {
+			return fmt.Errorf("failed to check token quota: %w", err)
+		}
+		if used >= quota.Monthly {
+			return fmt.Errorf("monthly token quota exceeded")
+		}
+	}
+
+	return nil
+}
+
+// usageMeter накапливает расход токенов всех вызовов LLM в рамках одного запроса к /ask.
+// Расход ведётся по моделям: после перехода на резервный эндпоинт вызовы одного запроса
+// может обслужить другая модель
+type usageMeter struct {
+	userID    string
+	role      string
+	datasetID *string
+
+	started time.Time
+	models  []*modelUsage
+}
+
+// modelUsage — расход токенов одной модели
+type modelUsage struct {
+	model      string
+	prompt     int
+	completion int
+	estimated  bool
+}
+
+func newUsageMeter(userID, role string, datasetID *string) *usageMeter {
+	return &usageMeter{
+		userID:    userID,
+		role:      role,
+		datasetID: datasetID,
+		started:   time.Now(),
+	}
+}
+
+// add учитывает один вызов LLM моделью model. Если провайдер не сообщил usage, токены оцениваются по тексту
+func (m *usageMeter) add(model string, reported *llm.Usage, messages []llm.Message, completion string) {
+	var u *modelUsage
+	for _, mu := range m.models {
+		if mu.model == model {
+			u = mu
+			break
+		}
+	}
+	if u == nil {
+		u = &modelUsage{model: model}
+		m.models = append(m.models, u)
+	}
+
+	if reported != nil && reported.PromptTokens+reported.CompletionTokens > 0 {
+		u.prompt += reported.PromptTokens
+		u.completion += reported.CompletionTokens
+		return
+	}
+
+	u.estimated = true
+	for _, msg := range messages {
+		u.prompt += rag.EstimateTokens(msg.Content)
+	}
+	u.completion += rag.EstimateTokens(completion)
+}
+
+// recordUsage сохраняет расход даже после отключения клиента: токены уже потрачены.
+// На каждую модель запроса пишется своя строка, задержка у всех строк — общая для запроса
+func (s *DatasetServiceImpl) recordUsage(ctx context.Context, m *usageMeter) {
+	latency := time.Since(m.started).Milliseconds()
+
+	for _, u := range m.models {
+		// вызов, упавший до ответа какого-либо эндпоинта, относится к основной модели
+		model := u.model
+		if model == "" {
+			model = s.cfg.LLM.Model
+		}
+
+		usage := &domain.LLMUsage{
+			UserID:           m.userID,
+			Role:             m.role,
+			DatasetID:        m.datasetID,
+			Model:            &model,
+			PromptTokens:     u.prompt,
+			CompletionTokens: u.completion,
+			TotalTokens:      u.prompt + u.completion,
+			Estimated:        u.estimated,
+			LatencyMs:        latency,
+		}
+
+		if err := s.repos.Usage.Create(context.WithoutCancel(ctx), usage); err != nil {
+			logger.Warn(fmt.Sprintf("failed to record llm usage for user %s: %v", m.userID, err))
+		}
+	}
+}
@@ -0,0 +1,25 @@
+create table index_jobs
+(
+    id           varchar(36)                                                   not null
+        primary key,
+    dataset_id   varchar(36)                                                   not null,
+    status       enum ('queued', 'running', 'failed', 'succeeded') default 'queued' not null,
+    attempts     int                                               default 0   not null,
+    max_attempts int                                               default 5   not null,
+    last_error   text                                                          null,
+    run_after    timestamp                                         default CURRENT_TIMESTAMP not null,
+    started_at   timestamp                                                     null,
+    finished_at  timestamp                                                     null,
+    created_at   timestamp                                         default CURRENT_TIMESTAMP null,
+    updated_at   timestamp                                         default CURRENT_TIMESTAMP null on update CURRENT_TIMESTAMP,
+    constraint fk_index_job_dataset
+        foreign key (dataset_id) references datasets (id)
+            on delete cascade
+)
+    charset = utf8mb4;
+
+create index idx_index_jobs_status_run_after
+    on index_jobs (status, run_after);
+
+create index idx_index_jobs_dataset_id
+    on index_jobs (dataset_id);
@@ -0,0 +1,2 @@
+ALTER TABLE index_jobs ADD COLUMN chunks_total INT NOT NULL DEFAULT 0;
+ALTER TABLE index_jobs ADD COLUMN chunks_embedded INT NOT NULL DEFAULT 0;
@@ -0,0 +1,26 @@
+create table dataset_versions
+(
+    id         varchar(36)                         not null
+        primary key,
+    dataset_id varchar(36)                         not null,
+    version    int                                 not null,
+    file_path  varchar(500)                        not null,
+    size       bigint    default 0                 not null,
+    created_by varchar(255)                        not null,
+    created_at timestamp default CURRENT_TIMESTAMP null,
+    indexed_at timestamp                           null,
+    constraint unique_dataset_version
+        unique (dataset_id, version),
+    constraint fk_dataset_version_dataset
+        foreign key (dataset_id) references datasets (id)
+            on delete cascade
+)
+    charset = utf8mb4;
+
+ALTER TABLE datasets ADD COLUMN current_version INT NOT NULL DEFAULT 1;
+
+INSERT INTO dataset_versions (id, dataset_id, version, file_path, created_by, created_at, indexed_at)
+SELECT UUID(), id, 1, file_path, COALESCE(author, user_id), created_at, indexed_at
+FROM datasets;
+
+ALTER TABLE index_jobs ADD COLUMN version INT NOT NULL DEFAULT 1;
@@ -0,0 +1 @@
+ALTER TABLE topics ADD COLUMN chunking_strategy VARCHAR(32) NOT NULL DEFAULT 'header';
@@ -0,0 +1,19 @@
+create table llm_usage
+(
+    id                varchar(36)                         not null
+        primary key,
+    user_id           varchar(255)                        not null,
+    role              varchar(32)                         not null,
+    dataset_id        varchar(36)                         null,
+    model             varchar(255)                        null,
+    prompt_tokens     int       default 0                 not null,
+    completion_tokens int       default 0                 not null,
+    total_tokens      int       default 0                 not null,
+    estimated         tinyint(1) default 0                not null,
+    latency_ms        int       default 0                 not null,
+    created_at        timestamp default CURRENT_TIMESTAMP not null
+)
+    charset = utf8mb4;
+
+create index idx_llm_usage_user_created
+    on llm_usage (user_id, created_at);
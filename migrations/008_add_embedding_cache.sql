@@ -0,0 +1,10 @@
+create table embedding_cache
+(
+    model      varchar(255)                        not null,
+    text_hash  char(64)                            not null,
+    dims       int                                 not null,
+    vector     mediumblob                          not null,
+    created_at timestamp default CURRENT_TIMESTAMP not null,
+    primary key (model, text_hash)
+)
+    charset = utf8mb4;
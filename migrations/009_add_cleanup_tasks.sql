@@ -0,0 +1,20 @@
+create table cleanup_tasks
+(
+    id         varchar(36)                                      not null
+        primary key,
+    dataset_id varchar(36)                                      not null,
+    kind       enum ('file', 'vectors', 'answer_cache')         not null,
+    target     varchar(500)                        default ''   not null,
+    attempts   int                                 default 0    not null,
+    last_error text                                             null,
+    run_after  timestamp                           default CURRENT_TIMESTAMP not null,
+    created_at timestamp                           default CURRENT_TIMESTAMP not null,
+    updated_at timestamp                           default CURRENT_TIMESTAMP not null on update CURRENT_TIMESTAMP
+)
+    charset = utf8mb4;
+
+create index idx_cleanup_tasks_run_after
+    on cleanup_tasks (run_after);
+
+create index idx_cleanup_tasks_dataset_id
+    on cleanup_tasks (dataset_id);
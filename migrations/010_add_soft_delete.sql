@@ -0,0 +1,8 @@
+ALTER TABLE datasets ADD COLUMN deleted_at TIMESTAMP NULL, ADD COLUMN deleted_by VARCHAR(255) NULL;
+CREATE INDEX idx_datasets_deleted_at ON datasets (deleted_at);
+
+ALTER TABLE topics ADD COLUMN deleted_at TIMESTAMP NULL, ADD COLUMN deleted_by VARCHAR(255) NULL;
+CREATE INDEX idx_topics_deleted_at ON topics (deleted_at);
+
+ALTER TABLE saved_chats ADD COLUMN deleted_at TIMESTAMP NULL, ADD COLUMN deleted_by VARCHAR(255) NULL;
+CREATE INDEX idx_saved_chats_deleted_at ON saved_chats (deleted_at);
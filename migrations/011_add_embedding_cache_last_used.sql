@@ -0,0 +1,2 @@
+ALTER TABLE embedding_cache ADD COLUMN last_used_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP;
+CREATE INDEX idx_embedding_cache_last_used_at ON embedding_cache (last_used_at);